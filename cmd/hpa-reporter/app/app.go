@@ -6,26 +6,49 @@ import (
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/collector"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	_ "github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/alertmanager"
+	_ "github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/opsgenie"
+	_ "github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/pagerduty"
+	_ "github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/slack"
+	_ "github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/stdout"
+	_ "github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/teams"
+	_ "github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/webhook"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/spool"
+	"github.com/k8shuginn/hpa_reporter/k8s"
 	"github.com/k8shuginn/hpa_reporter/logger"
 	"go.uber.org/zap"
+	"io"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 )
 
 var (
-	ConfigPath = kingpin.Flag("app.config", "config file path.").Default(config.DefaultConfigPath).String()
-	Name       = "hpa-reporter"
+	RunCmd      = kingpin.Command("run", "run the reporter.").Default()
+	ValidateCmd = kingpin.Command("validate", "validate the config file and print every problem.")
+
+	ConfigPath    = kingpin.Flag("app.config", "config file path.").Default(config.DefaultConfigPath).String()
+	WatchInterval = kingpin.Flag("app.config-watch-interval", "interval to check the config file for changes, 0 disables it.").Default(config.DefaultWatchInterval.String()).Duration()
+	DrainTimeout  = kingpin.Flag("app.drain-timeout", "how long the queued messages are delivered on shutdown before they are abandoned.").Default("20s").Duration()
+	MetricsAddr   = kingpin.Flag("app.metrics-address", "address of the /debug/vars metrics endpoint, empty disables it.").Default(":9090").String()
+	Name          = "hpa-reporter"
+
+	// Command is the selected sub command
+	Command string
 )
 
 func init() {
-	kingpin.Parse()
+	Command = kingpin.Parse()
 }
 
 type App struct {
 	appConfig *config.AppConfig
+	secrets   *k8s.SecretReader
 	rh        *reporter.Handler
 	ch        *collector.Handler
+	watcher   *config.Watcher
+	metrics   *http.Server
 }
 
 func NewApp() *App {
@@ -35,25 +58,46 @@ func NewApp() *App {
 func (a *App) Init() error {
 	var err error
 
-	// load config
-	a.appConfig, err = config.LoadConfig(*ConfigPath)
+	// load config, the config is logged before the references are resolved so no secret is written
+	rawConfig, err := loadConfig(*ConfigPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
-	logger.Info("config loaded", zap.Any("config", a.appConfig))
+	a.appConfig, err = a.resolve(rawConfig)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config: %w", err)
+	}
+	logger.Info("config loaded", zap.Any("config", rawConfig))
 
 	// create reporter handler
-	a.rh, err = reporter.NewReporterHandler(a.appConfig.Reporters)
+	a.rh, err = reporter.NewReporterHandler(a.appConfig.Reporters, a.appConfig.Route)
 	if err != nil {
 		return fmt.Errorf("failed to create reporter handler: %w", err)
 	}
 
+	// keep the messages that cannot be delivered
+	if a.appConfig.Spool.Path != "" {
+		s, err := spool.Open(a.appConfig.Spool.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open spool: %w", err)
+		}
+		a.rh.UseSpool(s)
+	}
+
 	// create collector handler
-	a.ch, err = collector.NewCollectorHandler(a.rh, a.appConfig.Hpa)
+	a.ch, err = collector.NewCollectorHandler(a.rh, a.appConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create collector handler: %w", err)
 	}
 
+	// watch config file
+	if *WatchInterval > 0 {
+		a.watcher, err = config.NewWatcher(*ConfigPath, *WatchInterval)
+		if err != nil {
+			return fmt.Errorf("failed to watch config: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -61,21 +105,161 @@ func (a *App) Execute() {
 	logger.Info(Name + " is started ... ")
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	a.ch.Run()
+	if a.appConfig.Spool.Path != "" {
+		go a.rh.RunSpool(a.appConfig.Spool)
+	}
+
+	if *MetricsAddr != "" {
+		a.metrics = newMetricsServer(*MetricsAddr)
+		go serveMetrics(a.metrics)
+	}
 
-	select {
-	case sig := <-sigChan:
-		a.Shutdown()
-		logger.Info(Name+" is shutdown", zap.String("signal", sig.String()))
+	var changed chan struct{}
+	if a.watcher != nil {
+		changed = a.watcher.C
+		go a.watcher.Run()
+	}
+
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig == syscall.SIGHUP {
+				a.Reload("signal")
+				continue
+			}
+
+			a.Shutdown()
+			logger.Info(Name+" is shutdown", zap.String("signal", sig.String()))
+			return
+		case <-changed:
+			a.Reload("file")
+		}
 	}
 }
 
+// Reload loads the config file again and swaps the hpa targets and the reporters.
+// the running config is kept if the new config is invalid.
+func (a *App) Reload(trigger string) {
+	logger.Info("reloading config", zap.String("trigger", trigger), zap.String("path", *ConfigPath))
+
+	rawConfig, err := loadConfig(*ConfigPath)
+	if err != nil {
+		logger.Error("failed to reload config, keeping the running config", zap.Error(err))
+		return
+	}
+
+	appConfig, err := a.resolve(rawConfig)
+	if err != nil {
+		logger.Error("failed to reload config, keeping the running config", zap.Error(err))
+		return
+	}
+
+	if err = collector.ValidateTargets(appConfig); err != nil {
+		logger.Error("failed to reload config, keeping the running config", zap.Error(err))
+		return
+	}
+
+	if err = a.rh.Reload(appConfig.Reporters, appConfig.Route); err != nil {
+		logger.Error("failed to reload config, keeping the running config", zap.Error(err))
+		return
+	}
+
+	if err = a.ch.Reload(appConfig); err != nil {
+		// unreachable, the targets were validated above
+		logger.Error("failed to reload collector config", zap.Error(err))
+		return
+	}
+
+	if appConfig.Spool != a.appConfig.Spool {
+		logger.Warn("spool config changed, it is applied on restart", zap.String("path", a.appConfig.Spool.Path))
+		appConfig.Spool = a.appConfig.Spool
+	}
+
+	a.appConfig = appConfig
+	logger.Info("config reloaded", zap.Any("config", rawConfig))
+}
+
+// resolve replaces the env, file and secret references of the reporter configs.
+// the secret reader is created on the first config that has secretRefs.
+func (a *App) resolve(rawConfig *config.AppConfig) (*config.AppConfig, error) {
+	if a.secrets == nil && rawConfig.HasSecretRefs() {
+		secrets, err := k8s.NewSecretReader(os.Getenv(collector.EnvKubeConfig))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create secret reader: %w", err)
+		}
+		a.secrets = secrets
+	}
+
+	if a.secrets == nil {
+		return config.Resolve(rawConfig, nil)
+	}
+	return config.Resolve(rawConfig, a.secrets)
+}
+
+// Validate checks the config file and prints every problem with its yaml path.
+// It returns the exit code, 1 if the config has problems.
+func Validate(w io.Writer) int {
+	data, err := os.ReadFile(*ConfigPath)
+	if err != nil {
+		fmt.Fprintf(w, "%s: %v\n", *ConfigPath, err)
+		return 1
+	}
+
+	problems := validate(data)
+	for _, p := range problems {
+		fmt.Fprintf(w, "%s: %s\n", *ConfigPath, p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%s: %d problem(s) found\n", *ConfigPath, len(problems))
+		return 1
+	}
+
+	fmt.Fprintf(w, "%s: ok\n", *ConfigPath)
+	return 0
+}
+
+// loadConfig reads the config file and validates the config and the reporter configs
+func loadConfig(path string) (*config.AppConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if problems := validate(data); len(problems) > 0 {
+		return nil, &config.ValidationError{Problems: problems}
+	}
+
+	appConfig, _ := config.ParseConfig(data)
+	return appConfig, nil
+}
+
+// validate returns the problems of the config and of the registered reporter types
+func validate(data []byte) []config.Problem {
+	appConfig, problems := config.ParseConfig(data)
+	if appConfig == nil {
+		return problems
+	}
+
+	return append(problems, reporter.Validate(appConfig.Reporters)...)
+}
+
+// Shutdown stops the collector first so no new message is raised, then drains the reporter queues until the drain timeout.
 func (a *App) Shutdown() {
+	if a.watcher != nil {
+		a.watcher.Stop()
+	}
+
 	a.ch.Shutdown()
 	logger.Info(Name + " is stopped ... ")
 
-	a.rh.Shutdown()
-	logger.Info("reporter is stopped ... ")
+	result := a.rh.Drain(*DrainTimeout)
+	logger.Info("reporter is stopped ... ", zap.Int("delivered", result.Delivered), zap.Int("failed", result.Failed),
+		zap.Int("abandoned", result.Abandoned), zap.Duration("drain timeout", *DrainTimeout))
+
+	if a.metrics != nil {
+		_ = a.metrics.Close()
+	}
 }
@@ -0,0 +1,26 @@
+package app
+
+import (
+	_ "expvar" // registers /debug/vars
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// newMetricsServer creates the server of the /debug/vars endpoint with the reporter queue metrics
+func newMetricsServer(address string) *http.Server {
+	return &http.Server{
+		Addr:              address,
+		Handler:           http.DefaultServeMux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+}
+
+// serveMetrics runs the metrics server until it is closed
+func serveMetrics(srv *http.Server) {
+	logger.Info("metrics server is started", zap.String("address", srv.Addr))
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Error("metrics server is stopped", zap.Error(err))
+	}
+}
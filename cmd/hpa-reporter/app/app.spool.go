@@ -0,0 +1,202 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/collector"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/spool"
+	"io"
+	"text/tabwriter"
+)
+
+var (
+	SpoolCmd       = kingpin.Command("spool", "inspect and replay the messages that could not be delivered.")
+	SpoolListCmd   = SpoolCmd.Command("list", "list the spooled messages.")
+	SpoolReplayCmd = SpoolCmd.Command("replay", "deliver the spooled messages again and remove the delivered ones.")
+	SpoolPurgeCmd  = SpoolCmd.Command("purge", "remove the spooled messages.")
+
+	SpoolReporter = SpoolCmd.Flag("reporter", "only the messages of the reporter.").String()
+	SpoolIDs      = SpoolCmd.Flag("id", "only the message with the id, can be repeated.").Strings()
+)
+
+// Spool runs the spool sub command and returns the exit code
+func Spool(w io.Writer) int {
+	var err error
+	switch Command {
+	case SpoolListCmd.FullCommand():
+		err = spoolList(w)
+	case SpoolReplayCmd.FullCommand():
+		err = spoolReplay(w)
+	case SpoolPurgeCmd.FullCommand():
+		err = spoolPurge(w)
+	default:
+		err = fmt.Errorf("unknown command %q", Command)
+	}
+
+	if err != nil {
+		fmt.Fprintf(w, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// openSpool opens the spool of the config file
+func openSpool() (*config.AppConfig, *spool.Spool, error) {
+	appConfig, err := loadConfig(*ConfigPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if appConfig.Spool.Path == "" {
+		return nil, nil, errors.New("spool.path is not set in the config")
+	}
+
+	s, err := spool.Open(appConfig.Spool.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return appConfig, s, nil
+}
+
+// selected reports whether the entry matches the --reporter and --id flags
+func selected(e spool.Entry) bool {
+	if *SpoolReporter != "" && e.Reporter != *SpoolReporter {
+		return false
+	}
+	if len(*SpoolIDs) == 0 {
+		return true
+	}
+	for _, id := range *SpoolIDs {
+		if e.ID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// spoolList prints the selected entries as a table
+func spoolList(w io.Writer) error {
+	_, s, err := openSpool()
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tREPORTER\tFAILED AT\tATTEMPTS\tLEVEL\tHPA\tALERT\tERROR")
+	for _, e := range entries {
+		if !selected(e) {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s/%s\t%s\t%s\n", e.ID, e.Reporter, e.FailedAt.Format(collector.TimeFormat), e.Attempts,
+			e.Message.Level, e.Message.Namespace, e.Message.Name, e.Message.Alert, e.Error)
+	}
+
+	return tw.Flush()
+}
+
+// spoolReplay delivers the selected entries with the reporters of the config and removes the delivered ones
+func spoolReplay(w io.Writer) error {
+	rawConfig, s, err := openSpool()
+	if err != nil {
+		return err
+	}
+	appConfig, err := NewApp().resolve(rawConfig)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config: %w", err)
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		return err
+	}
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	// a newer message of the same alert makes an entry stale, a replayed firing message would reopen a resolved alert
+	latest := spool.Latest(entries)
+
+	reporters := make(map[string]reporter.Reporter)
+	var delivered, failed int
+	for _, e := range entries {
+		if !selected(e) {
+			continue
+		}
+		if e.FailedAt.Before(latest[spool.Key(e.Reporter, e.Message)]) {
+			if _, err = s.Remove(e.ID); err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s: superseded by a newer message, removed\n", e.ID)
+			continue
+		}
+
+		rep, ok := reporters[e.Reporter]
+		if !ok {
+			cfg, found := findReporter(appConfig.Reporters, e.Reporter)
+			if !found {
+				fmt.Fprintf(w, "%s: reporter %q is not in the config\n", e.ID, e.Reporter)
+				failed++
+				continue
+			}
+			if rep, err = reporter.New(cfg, shutdown); err != nil {
+				return err
+			}
+			reporters[e.Reporter] = rep
+		}
+
+		if err = rep.Report(e.Message); err != nil {
+			fmt.Fprintf(w, "%s: %v\n", e.ID, err)
+			if err = s.Failed(e.ID, err); err != nil {
+				return err
+			}
+			failed++
+			continue
+		}
+		if _, err = s.Remove(e.ID); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s: delivered to %s\n", e.ID, e.Reporter)
+		delivered++
+	}
+
+	fmt.Fprintf(w, "%d delivered, %d failed\n", delivered, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d message(s) could not be delivered", failed)
+	}
+	return nil
+}
+
+// spoolPurge removes the selected entries
+func spoolPurge(w io.Writer) error {
+	_, s, err := openSpool()
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.Purge(selected)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%d message(s) removed\n", removed)
+	return nil
+}
+
+// findReporter returns the reporter config with the name
+func findReporter(reporters []config.Reporter, name string) (config.Reporter, bool) {
+	for _, r := range reporters {
+		if r.Name == name {
+			return r, true
+		}
+	}
+
+	return config.Reporter{}, false
+}
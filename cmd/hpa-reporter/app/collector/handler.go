@@ -1,13 +1,18 @@
 package collector
 
 import (
+	"errors"
 	"fmt"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
 	"github.com/k8shuginn/hpa_reporter/k8s"
 	"github.com/k8shuginn/hpa_reporter/logger"
 	"go.uber.org/zap"
+	"k8s.io/client-go/tools/cache"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
@@ -20,27 +25,39 @@ type Reporter interface {
 
 // Handler is kubernetes hpa event handler.
 type Handler struct {
-	reporter  Reporter
-	client    *k8s.Client
-	hpaTarget map[string]int32
+	reporter Reporter
+	client   *k8s.Client
+	base     *targets
+	targets  atomic.Pointer[targets]
+	policyMu sync.Mutex
+	policies map[string][]rule
+	convert  convertFunc
+	// list returns the hpas of the informer cache
+	list     func() []interface{}
+	tracker  *tracker
+	shutdown chan struct{}
 
-	OnAddFunc    // unused
+	OnAddFunc    // used
 	OnUpdateFunc // used
-	OnDeleteFunc // unused
+	OnDeleteFunc // used
 }
 
 // NewCollectorHandler is a constructor that creates a new handler.
-func NewCollectorHandler(reporter Reporter, configs []config.HpaConfig) (*Handler, error) {
+func NewCollectorHandler(reporter Reporter, appConfig *config.AppConfig) (*Handler, error) {
 	h := &Handler{
-		reporter:  reporter,
-		hpaTarget: make(map[string]int32),
+		reporter: reporter,
+		tracker:  newTracker(appConfig.Alert.RenotifyInterval),
+		shutdown: make(chan struct{}),
+		policies: make(map[string][]rule),
 	}
 
 	// set hpa target
-	for _, cfg := range configs {
-		k := cfg.Namespace + "/" + cfg.Name
-		h.hpaTarget[k] = cfg.Threshold
+	var err error
+	h.base, err = newTargets(appConfig.Hpa, appConfig.HpaDefault)
+	if err != nil {
+		return nil, fmt.Errorf("[collector] invalid hpa config: %w", err)
 	}
+	h.targets.Store(h.base)
 
 	// create k8s client
 	client, err := k8s.NewClient(h, os.Getenv(EnvKubeConfig))
@@ -48,42 +65,93 @@ func NewCollectorHandler(reporter Reporter, configs []config.HpaConfig) (*Handle
 		return nil, fmt.Errorf("[collector] failed to create k8s client: %w", err)
 	}
 	h.client = client
+	h.list = client.ListHPAs
 
 	// set hpa version
 	version := h.client.GetHPAVersion()
-	switch version {
-	case "v1":
-		h.OnAddFunc = h.v1Add
-		h.OnUpdateFunc = h.v1Update
-		h.OnDeleteFunc = h.v1Delete
-	case "v2":
-		h.OnAddFunc = h.v2Add
-		h.OnUpdateFunc = h.v2Update
-		h.OnDeleteFunc = h.v2Delete
-	case "v2beta1":
-		h.OnAddFunc = h.v2beta1Add
-		h.OnUpdateFunc = h.v2beta1Update
-		h.OnDeleteFunc = h.v2beta1Delete
-	case "v2beta2":
-		h.OnAddFunc = h.v2beta2Add
-		h.OnUpdateFunc = h.v2beta2Update
-		h.OnDeleteFunc = h.v2beta2Delete
-	default:
-		return nil, fmt.Errorf("[collector] unsupported hpa version: %s", h.client.GetHPAVersion())
+	h.convert, err = converterFor(version)
+	if err != nil {
+		return nil, fmt.Errorf("[collector] %w", err)
 	}
+	h.OnAddFunc = h.onAdd
+	h.OnUpdateFunc = h.onUpdate
+	h.OnDeleteFunc = h.onDelete
 	logger.Info("[collector] k8s client created", zap.String("hpa version", version))
 
+	// watch HPAReportPolicy if the crd is installed
+	err = h.client.WatchPolicies(cache.ResourceEventHandlerFuncs{
+		AddFunc:    h.onPolicyAdd,
+		UpdateFunc: h.onPolicyUpdate,
+		DeleteFunc: h.onPolicyDelete,
+	})
+	switch {
+	case errors.Is(err, k8s.ErrPolicyNotInstalled):
+		logger.Info("[collector] HPAReportPolicy crd is not installed, policies are disabled")
+	case err != nil:
+		logger.Warn("[collector] failed to watch HPAReportPolicy, policies are disabled", zap.Error(err))
+	}
+
 	return h, nil
 }
 
 // Run is a method that starts the handler.
 func (h *Handler) Run() {
 	h.client.Start()
+	go h.renotify()
 	logger.Info("[collector] is started ... ")
 }
 
+// ValidateTargets compiles the hpa rules of the config without applying them.
+func ValidateTargets(appConfig *config.AppConfig) error {
+	if _, err := newTargets(appConfig.Hpa, appConfig.HpaDefault); err != nil {
+		return fmt.Errorf("invalid hpa config: %w", err)
+	}
+
+	return nil
+}
+
+// Reload swaps the hpa rules and the alert settings with the new config.
+// the running rules are kept if the new rules cannot be compiled.
+func (h *Handler) Reload(appConfig *config.AppConfig) error {
+	base, err := newTargets(appConfig.Hpa, appConfig.HpaDefault)
+	if err != nil {
+		return fmt.Errorf("invalid hpa config: %w", err)
+	}
+
+	h.policyMu.Lock()
+	h.base = base
+	h.storeTargets()
+	h.policyMu.Unlock()
+
+	h.tracker.setRenotifyInterval(appConfig.Alert.RenotifyInterval)
+	h.resync()
+	logger.Info("[collector] config reloaded")
+
+	return nil
+}
+
 // Shutdown is a method that stops the handler.
 func (h *Handler) Shutdown() {
 	h.client.Stop()
+	close(h.shutdown)
 	logger.Info("[collector] is stopped ... ")
 }
+
+// renotify re-sends alerts that are still firing after the renotify interval.
+func (h *Handler) renotify() {
+	ticker := time.NewTicker(renotifyCheckPeriod)
+	defer ticker.Stop()
+
+LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			for _, msg := range h.tracker.due() {
+				logger.Debug("[collector] re-notify firing hpa", zap.String("name", msg.Name), zap.String("namespace", msg.Namespace), zap.String("level", msg.Level))
+				h.reporter.Report(msg)
+			}
+		case <-h.shutdown:
+			break LOOP
+		}
+	}
+}
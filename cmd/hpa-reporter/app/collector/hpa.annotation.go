@@ -0,0 +1,75 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+)
+
+const (
+	AnnotationPrefix = "hpa-reporter.k8shuginn.io/"
+
+	// AnnotationEnabled opts the hpa in ("true") or out ("false") regardless of the config
+	AnnotationEnabled = AnnotationPrefix + "enabled"
+	// AnnotationThreshold sets the threshold of the hpa, it also opts the hpa in
+	AnnotationThreshold = AnnotationPrefix + "threshold"
+	// AnnotationRoute is a comma separated list of reporter names that get the alerts of the hpa
+	AnnotationRoute = AnnotationPrefix + "route"
+
+	// DefaultThreshold is used when an hpa opts in without a threshold and no config rule matches
+	DefaultThreshold = "80%"
+)
+
+var (
+	defaultThreshold = config.MustParseThreshold(DefaultThreshold)
+)
+
+// resolveTarget merges the annotations of the hpa with the config rule that matches it.
+// annotations win over the config, so an hpa owner can opt in, opt out or override the threshold.
+// annotations are read on every event, so a change takes effect with the next update.
+func (h *Handler) resolveTarget(s *HPAState) (target, bool) {
+	t, ok := h.targets.Load().match(s)
+
+	if v, set := s.Annotations[AnnotationEnabled]; set {
+		enabled, err := strconv.ParseBool(v)
+		switch {
+		case err != nil:
+			logger.Warn("[collector] invalid annotation", zap.String("hpa", s.Key()), zap.String("annotation", AnnotationEnabled), zap.String("value", v))
+		case !enabled:
+			return target{}, false
+		case !ok:
+			t, ok = target{threshold: defaultThreshold}, true
+		}
+	}
+
+	if v, set := s.Annotations[AnnotationThreshold]; set {
+		threshold, err := config.ParseThreshold(v)
+		switch {
+		case err != nil:
+			logger.Warn("[collector] invalid annotation", zap.String("hpa", s.Key()), zap.String("annotation", AnnotationThreshold), zap.Error(err))
+		case threshold.IsZero():
+			// 0 would raise a warning at every replica count
+			logger.Warn("[collector] invalid annotation", zap.String("hpa", s.Key()), zap.String("annotation", AnnotationThreshold), zap.String("value", v),
+				zap.String("reason", "threshold must be greater than 0"))
+		default:
+			t.threshold, ok = threshold, true
+		}
+	}
+
+	if !ok {
+		return target{}, false
+	}
+
+	if v := s.Annotations[AnnotationRoute]; v != "" {
+		t.receivers = nil
+		for _, receiver := range strings.Split(v, ",") {
+			if receiver = strings.TrimSpace(receiver); receiver != "" {
+				t.receivers = append(t.receivers, receiver)
+			}
+		}
+	}
+
+	return t, true
+}
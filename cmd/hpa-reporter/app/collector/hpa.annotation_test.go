@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"reflect"
+	"testing"
+)
+
+func TestResolveTarget(t *testing.T) {
+	targets, _ := newTargets([]config.HpaConfig{
+		{Namespace: "test", Name: "my-hpa", Threshold: config.MustParseThreshold("5")},
+	}, config.HpaDefaultConfig{})
+	h := &Handler{}
+	h.targets.Store(targets)
+
+	tests := []struct {
+		name        string
+		hpaName     string
+		annotations map[string]string
+		want        bool
+		threshold   string
+		receivers   []string
+	}{
+		{"config only", "my-hpa", nil, true, "5", nil},
+		{"not targeted", "other", nil, false, "", nil},
+		{"opt in with threshold", "other", map[string]string{AnnotationThreshold: "8"}, true, "8", nil},
+		{"opt in without threshold", "other", map[string]string{AnnotationEnabled: "true"}, true, DefaultThreshold, nil},
+		{"threshold overrides config", "my-hpa", map[string]string{AnnotationThreshold: "max-1"}, true, "max-1", nil},
+		{"zero threshold is ignored", "my-hpa", map[string]string{AnnotationThreshold: "0"}, true, "5", nil},
+		{"zero threshold does not opt in", "other", map[string]string{AnnotationThreshold: "0"}, false, "", nil},
+		{"opt out", "my-hpa", map[string]string{AnnotationEnabled: "false", AnnotationThreshold: "8"}, false, "", nil},
+		{"route", "my-hpa", map[string]string{AnnotationRoute: "team-payments, oncall"}, true, "5", []string{"team-payments", "oncall"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := h.resolveTarget(&HPAState{Namespace: "test", Name: tt.hpaName, Annotations: tt.annotations})
+			if ok != tt.want {
+				t.Fatalf("expected targeted %v, got %v", tt.want, ok)
+			}
+			if !ok {
+				return
+			}
+			if target.threshold.String() != tt.threshold {
+				t.Errorf("expected threshold %s, got %s", tt.threshold, target.threshold)
+			}
+			if !reflect.DeepEqual(target.receivers, tt.receivers) {
+				t.Errorf("expected receivers %v, got %v", tt.receivers, target.receivers)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	v1 "k8s.io/api/autoscaling/v1"
+	v2 "k8s.io/api/autoscaling/v2"
+	v2beta1 "k8s.io/api/autoscaling/v2beta1"
+	v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// AnnotationV1Conditions holds the status conditions of an autoscaling/v1 hpa
+	AnnotationV1Conditions = "autoscaling.alpha.kubernetes.io/conditions"
+)
+
+// convertFunc converts an informer object into HPAState
+type convertFunc func(obj interface{}) (*HPAState, error)
+
+// converterFor returns the convertFunc for the hpa api version
+func converterFor(version string) (convertFunc, error) {
+	switch version {
+	case "v1":
+		return convertV1, nil
+	case "v2":
+		return convertV2, nil
+	case "v2beta1":
+		return convertV2beta1, nil
+	case "v2beta2":
+		return convertV2beta2, nil
+	default:
+		return nil, fmt.Errorf("unsupported hpa version: %s", version)
+	}
+}
+
+// unwrapTombstone returns the last known object of a deleted hpa
+func unwrapTombstone(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+
+	return obj
+}
+
+// convertV1 converts v1.HorizontalPodAutoscaler into HPAState
+func convertV1(obj interface{}) (*HPAState, error) {
+	object, ok := unwrapTombstone(obj).(*v1.HorizontalPodAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	s := newState(object.ObjectMeta, object.Spec.MinReplicas, object.Spec.MaxReplicas,
+		object.Status.CurrentReplicas, object.Status.DesiredReplicas, object.Status.LastScaleTime)
+	s.Target = Target{APIVersion: object.Spec.ScaleTargetRef.APIVersion, Kind: object.Spec.ScaleTargetRef.Kind, Name: object.Spec.ScaleTargetRef.Name}
+
+	// v1 has no conditions field, the controller stores them in an annotation
+	if raw, ok := object.Annotations[AnnotationV1Conditions]; ok {
+		var conditions []v1.HorizontalPodAutoscalerCondition
+		if err := json.Unmarshal([]byte(raw), &conditions); err == nil {
+			for _, c := range conditions {
+				s.Conditions = append(s.Conditions, newCondition(string(c.Type), string(c.Status), c.Reason, c.Message, c.LastTransitionTime))
+			}
+		}
+	}
+
+	if object.Spec.TargetCPUUtilizationPercentage != nil {
+		m := Metric{Type: "Resource", Name: "cpu", Target: utilization(object.Spec.TargetCPUUtilizationPercentage)}
+		if object.Status.CurrentCPUUtilizationPercentage != nil {
+			m.Current = utilization(object.Status.CurrentCPUUtilizationPercentage)
+		}
+		s.Metrics = append(s.Metrics, m)
+	}
+
+	return s, nil
+}
+
+// convertV2 converts v2.HorizontalPodAutoscaler into HPAState
+func convertV2(obj interface{}) (*HPAState, error) {
+	object, ok := unwrapTombstone(obj).(*v2.HorizontalPodAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	s := newState(object.ObjectMeta, object.Spec.MinReplicas, object.Spec.MaxReplicas,
+		object.Status.CurrentReplicas, object.Status.DesiredReplicas, object.Status.LastScaleTime)
+	s.Target = Target{APIVersion: object.Spec.ScaleTargetRef.APIVersion, Kind: object.Spec.ScaleTargetRef.Kind, Name: object.Spec.ScaleTargetRef.Name}
+
+	for _, c := range object.Status.Conditions {
+		s.Conditions = append(s.Conditions, newCondition(string(c.Type), string(c.Status), c.Reason, c.Message, c.LastTransitionTime))
+	}
+
+	current := make(map[string]string)
+	for _, m := range object.Status.CurrentMetrics {
+		var name string
+		var value v2.MetricValueStatus
+		switch {
+		case m.Resource != nil:
+			name, value = string(m.Resource.Name), m.Resource.Current
+		case m.ContainerResource != nil:
+			name, value = string(m.ContainerResource.Name), m.ContainerResource.Current
+		case m.Pods != nil:
+			name, value = m.Pods.Metric.Name, m.Pods.Current
+		case m.Object != nil:
+			name, value = m.Object.Metric.Name, m.Object.Current
+		case m.External != nil:
+			name, value = m.External.Metric.Name, m.External.Current
+		}
+		current[string(m.Type)+"/"+name] = metricValue(value.AverageUtilization, value.AverageValue, value.Value)
+	}
+
+	for _, m := range object.Spec.Metrics {
+		var name string
+		var target v2.MetricTarget
+		switch {
+		case m.Resource != nil:
+			name, target = string(m.Resource.Name), m.Resource.Target
+		case m.ContainerResource != nil:
+			name, target = string(m.ContainerResource.Name), m.ContainerResource.Target
+		case m.Pods != nil:
+			name, target = m.Pods.Metric.Name, m.Pods.Target
+		case m.Object != nil:
+			name, target = m.Object.Metric.Name, m.Object.Target
+		case m.External != nil:
+			name, target = m.External.Metric.Name, m.External.Target
+		}
+		s.Metrics = append(s.Metrics, Metric{
+			Type:    string(m.Type),
+			Name:    name,
+			Target:  metricValue(target.AverageUtilization, target.AverageValue, target.Value),
+			Current: current[string(m.Type)+"/"+name],
+		})
+	}
+
+	return s, nil
+}
+
+// convertV2beta1 converts v2beta1.HorizontalPodAutoscaler into HPAState
+func convertV2beta1(obj interface{}) (*HPAState, error) {
+	object, ok := unwrapTombstone(obj).(*v2beta1.HorizontalPodAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	s := newState(object.ObjectMeta, object.Spec.MinReplicas, object.Spec.MaxReplicas,
+		object.Status.CurrentReplicas, object.Status.DesiredReplicas, object.Status.LastScaleTime)
+	s.Target = Target{APIVersion: object.Spec.ScaleTargetRef.APIVersion, Kind: object.Spec.ScaleTargetRef.Kind, Name: object.Spec.ScaleTargetRef.Name}
+
+	for _, c := range object.Status.Conditions {
+		s.Conditions = append(s.Conditions, newCondition(string(c.Type), string(c.Status), c.Reason, c.Message, c.LastTransitionTime))
+	}
+
+	current := make(map[string]string)
+	for _, m := range object.Status.CurrentMetrics {
+		switch {
+		case m.Resource != nil:
+			current[string(m.Type)+"/"+string(m.Resource.Name)] = metricValue(m.Resource.CurrentAverageUtilization, &m.Resource.CurrentAverageValue, nil)
+		case m.ContainerResource != nil:
+			current[string(m.Type)+"/"+string(m.ContainerResource.Name)] = metricValue(m.ContainerResource.CurrentAverageUtilization, &m.ContainerResource.CurrentAverageValue, nil)
+		case m.Pods != nil:
+			current[string(m.Type)+"/"+m.Pods.MetricName] = metricValue(nil, &m.Pods.CurrentAverageValue, nil)
+		case m.Object != nil:
+			current[string(m.Type)+"/"+m.Object.MetricName] = metricValue(nil, m.Object.AverageValue, &m.Object.CurrentValue)
+		case m.External != nil:
+			current[string(m.Type)+"/"+m.External.MetricName] = metricValue(nil, m.External.CurrentAverageValue, &m.External.CurrentValue)
+		}
+	}
+
+	for _, m := range object.Spec.Metrics {
+		metric := Metric{Type: string(m.Type)}
+		switch {
+		case m.Resource != nil:
+			metric.Name = string(m.Resource.Name)
+			metric.Target = metricValue(m.Resource.TargetAverageUtilization, m.Resource.TargetAverageValue, nil)
+		case m.ContainerResource != nil:
+			metric.Name = string(m.ContainerResource.Name)
+			metric.Target = metricValue(m.ContainerResource.TargetAverageUtilization, m.ContainerResource.TargetAverageValue, nil)
+		case m.Pods != nil:
+			metric.Name = m.Pods.MetricName
+			metric.Target = metricValue(nil, &m.Pods.TargetAverageValue, nil)
+		case m.Object != nil:
+			metric.Name = m.Object.MetricName
+			metric.Target = metricValue(nil, m.Object.AverageValue, &m.Object.TargetValue)
+		case m.External != nil:
+			metric.Name = m.External.MetricName
+			metric.Target = metricValue(nil, m.External.TargetAverageValue, m.External.TargetValue)
+		}
+		metric.Current = current[metric.Type+"/"+metric.Name]
+		s.Metrics = append(s.Metrics, metric)
+	}
+
+	return s, nil
+}
+
+// convertV2beta2 converts v2beta2.HorizontalPodAutoscaler into HPAState
+func convertV2beta2(obj interface{}) (*HPAState, error) {
+	object, ok := unwrapTombstone(obj).(*v2beta2.HorizontalPodAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	s := newState(object.ObjectMeta, object.Spec.MinReplicas, object.Spec.MaxReplicas,
+		object.Status.CurrentReplicas, object.Status.DesiredReplicas, object.Status.LastScaleTime)
+	s.Target = Target{APIVersion: object.Spec.ScaleTargetRef.APIVersion, Kind: object.Spec.ScaleTargetRef.Kind, Name: object.Spec.ScaleTargetRef.Name}
+
+	for _, c := range object.Status.Conditions {
+		s.Conditions = append(s.Conditions, newCondition(string(c.Type), string(c.Status), c.Reason, c.Message, c.LastTransitionTime))
+	}
+
+	current := make(map[string]string)
+	for _, m := range object.Status.CurrentMetrics {
+		var name string
+		var value v2beta2.MetricValueStatus
+		switch {
+		case m.Resource != nil:
+			name, value = string(m.Resource.Name), m.Resource.Current
+		case m.ContainerResource != nil:
+			name, value = string(m.ContainerResource.Name), m.ContainerResource.Current
+		case m.Pods != nil:
+			name, value = m.Pods.Metric.Name, m.Pods.Current
+		case m.Object != nil:
+			name, value = m.Object.Metric.Name, m.Object.Current
+		case m.External != nil:
+			name, value = m.External.Metric.Name, m.External.Current
+		}
+		current[string(m.Type)+"/"+name] = metricValue(value.AverageUtilization, value.AverageValue, value.Value)
+	}
+
+	for _, m := range object.Spec.Metrics {
+		var name string
+		var target v2beta2.MetricTarget
+		switch {
+		case m.Resource != nil:
+			name, target = string(m.Resource.Name), m.Resource.Target
+		case m.ContainerResource != nil:
+			name, target = string(m.ContainerResource.Name), m.ContainerResource.Target
+		case m.Pods != nil:
+			name, target = m.Pods.Metric.Name, m.Pods.Target
+		case m.Object != nil:
+			name, target = m.Object.Metric.Name, m.Object.Target
+		case m.External != nil:
+			name, target = m.External.Metric.Name, m.External.Target
+		}
+		s.Metrics = append(s.Metrics, Metric{
+			Type:    string(m.Type),
+			Name:    name,
+			Target:  metricValue(target.AverageUtilization, target.AverageValue, target.Value),
+			Current: current[string(m.Type)+"/"+name],
+		})
+	}
+
+	return s, nil
+}
+
+// newState creates HPAState with the fields shared by all api versions
+func newState(meta metav1.ObjectMeta, minReplicas *int32, maxReplicas, currentReplicas, desiredReplicas int32, lastScaleTime *metav1.Time) *HPAState {
+	s := &HPAState{
+		Namespace:   meta.Namespace,
+		Name:        meta.Name,
+		Labels:      meta.Labels,
+		Annotations: meta.Annotations,
+		Spec: Spec{
+			MinReplicas: 1,
+			MaxReplicas: maxReplicas,
+		},
+		Status: Status{
+			CurrentReplicas: currentReplicas,
+			DesiredReplicas: desiredReplicas,
+		},
+	}
+	if minReplicas != nil {
+		s.Spec.MinReplicas = *minReplicas
+	}
+	if lastScaleTime != nil {
+		t := lastScaleTime.Time
+		s.Status.LastScaleTime = &t
+	}
+
+	return s
+}
+
+// newCondition creates a Condition
+func newCondition(conditionType, status, reason, message string, lastTransitionTime metav1.Time) Condition {
+	return Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: lastTransitionTime.Time,
+	}
+}
+
+// metricValue formats the first set value of a metric target or status
+func metricValue(averageUtilization *int32, averageValue, value *resource.Quantity) string {
+	switch {
+	case averageUtilization != nil:
+		return utilization(averageUtilization)
+	case averageValue != nil && !averageValue.IsZero():
+		return averageValue.String()
+	case value != nil:
+		return value.String()
+	default:
+		return ""
+	}
+}
+
+// utilization formats a utilization percentage
+func utilization(v *int32) string {
+	return fmt.Sprintf("%d%%", *v)
+}
@@ -0,0 +1,94 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+)
+
+const (
+	ConditionAbleToScale    = "AbleToScale"
+	ConditionScalingActive  = "ScalingActive"
+	ConditionScalingLimited = "ScalingLimited"
+
+	ReasonTooManyReplicas = "TooManyReplicas"
+	ReasonScalingDisabled = "ScalingDisabled"
+)
+
+var (
+	// alertKinds is every kind of alert evaluated for an hpa
+	alertKinds = []string{
+		message.AlertReplicas,
+		message.AlertScalingLimited,
+		message.AlertMetricsUnavailable,
+		message.AlertUnableToScale,
+	}
+)
+
+// alert is the evaluated result of one kind of alert. level is empty if it is not firing.
+type alert struct {
+	kind      string
+	level     string
+	condition *Condition
+}
+
+// evaluate returns the result of every alert kind of the hpa for the threshold
+func evaluate(s *HPAState, threshold config.Threshold) []alert {
+	return []alert{
+		evaluateReplicas(s, threshold.Resolve(s.Spec.MaxReplicas)),
+		evaluateScalingLimited(s),
+		evaluateMetricsUnavailable(s),
+		evaluateUnableToScale(s),
+	}
+}
+
+// evaluateReplicas compares the current and desired replicas with the threshold and max replicas.
+// the desired replicas reach max before the current replicas catch up, so it raises the level earlier.
+func evaluateReplicas(s *HPAState, threshold int32) alert {
+	a := alert{kind: message.AlertReplicas}
+	replicas := s.Status.CurrentReplicas
+	if s.Status.DesiredReplicas > replicas {
+		replicas = s.Status.DesiredReplicas
+	}
+
+	switch {
+	case replicas >= s.Spec.MaxReplicas:
+		a.level = message.LevelCritical
+	case replicas >= threshold:
+		a.level = message.LevelWarning
+	}
+
+	return a
+}
+
+// evaluateScalingLimited fires when the hpa wants more replicas than max (ScalingLimited=True, reason=TooManyReplicas)
+func evaluateScalingLimited(s *HPAState) alert {
+	a := alert{kind: message.AlertScalingLimited}
+	if c, ok := s.Condition(ConditionScalingLimited); ok && c.Status == "True" && c.Reason == ReasonTooManyReplicas {
+		a.level = message.LevelCritical
+		a.condition = &c
+	}
+
+	return a
+}
+
+// evaluateMetricsUnavailable fires when the hpa cannot fetch its metrics (ScalingActive=False)
+func evaluateMetricsUnavailable(s *HPAState) alert {
+	a := alert{kind: message.AlertMetricsUnavailable}
+	if c, ok := s.Condition(ConditionScalingActive); ok && c.Status == "False" && c.Reason != ReasonScalingDisabled {
+		a.level = message.LevelWarning
+		a.condition = &c
+	}
+
+	return a
+}
+
+// evaluateUnableToScale fires when the hpa cannot get or update the scale of its target (AbleToScale=False)
+func evaluateUnableToScale(s *HPAState) alert {
+	a := alert{kind: message.AlertUnableToScale}
+	if c, ok := s.Condition(ConditionAbleToScale); ok && c.Status == "False" {
+		a.level = message.LevelCritical
+		a.condition = &c
+	}
+
+	return a
+}
@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"testing"
+)
+
+func TestEvaluateConditions(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []Condition
+		want       map[string]string
+	}{
+		{
+			name: "healthy",
+			conditions: []Condition{
+				{Type: ConditionAbleToScale, Status: "True", Reason: "ReadyForNewScale"},
+				{Type: ConditionScalingActive, Status: "True", Reason: "ValidMetricFound"},
+				{Type: ConditionScalingLimited, Status: "False", Reason: "DesiredWithinRange"},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "wants more replicas than max",
+			conditions: []Condition{
+				{Type: ConditionScalingLimited, Status: "True", Reason: ReasonTooManyReplicas, Message: "the desired replica count is more than the maximum replica count"},
+			},
+			want: map[string]string{message.AlertScalingLimited: message.LevelCritical},
+		},
+		{
+			name: "limited by min replicas",
+			conditions: []Condition{
+				{Type: ConditionScalingLimited, Status: "True", Reason: "TooFewReplicas"},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "cannot fetch metrics",
+			conditions: []Condition{
+				{Type: ConditionScalingActive, Status: "False", Reason: "FailedGetResourceMetric", Message: "unable to get metrics"},
+			},
+			want: map[string]string{message.AlertMetricsUnavailable: message.LevelWarning},
+		},
+		{
+			name: "scaling disabled",
+			conditions: []Condition{
+				{Type: ConditionScalingActive, Status: "False", Reason: ReasonScalingDisabled},
+			},
+			want: map[string]string{},
+		},
+		{
+			name: "unable to scale",
+			conditions: []Condition{
+				{Type: ConditionAbleToScale, Status: "False", Reason: "FailedGetScale"},
+			},
+			want: map[string]string{message.AlertUnableToScale: message.LevelCritical},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &HPAState{
+				Spec:       Spec{MaxReplicas: 10},
+				Status:     Status{CurrentReplicas: 1},
+				Conditions: tt.conditions,
+			}
+
+			got := make(map[string]string)
+			for _, a := range evaluate(state, config.MustParseThreshold("5")) {
+				if a.level == "" {
+					continue
+				}
+				got[a.kind] = a.level
+				if a.condition == nil {
+					t.Errorf("%s: expected condition to be set", a.kind)
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for kind, level := range tt.want {
+				if got[kind] != level {
+					t.Errorf("%s: expected level %s, got %s", kind, level, got[kind])
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateReplicas(t *testing.T) {
+	tests := []struct {
+		name             string
+		current, desired int32
+		want             string
+	}{
+		{"below threshold", 3, 3, ""},
+		{"current reached threshold", 5, 5, message.LevelWarning},
+		{"desired reached threshold", 3, 6, message.LevelWarning},
+		{"current reached max", 10, 10, message.LevelCritical},
+		{"desired reached max before current", 6, 10, message.LevelCritical},
+		{"scaling in from max", 10, 4, message.LevelCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := &HPAState{
+				Spec:   Spec{MaxReplicas: 10},
+				Status: Status{CurrentReplicas: tt.current, DesiredReplicas: tt.desired},
+			}
+
+			if got := evaluateReplicas(state, 5); got.level != tt.want {
+				t.Errorf("expected level %q, got %q", tt.want, got.level)
+			}
+		})
+	}
+}
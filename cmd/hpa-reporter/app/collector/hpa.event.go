@@ -1,290 +1,89 @@
 package collector
 
 import (
-	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
 	"github.com/k8shuginn/hpa_reporter/logger"
 	"go.uber.org/zap"
-	v1 "k8s.io/api/autoscaling/v1"
-	v2 "k8s.io/api/autoscaling/v2"
-	v2beta1 "k8s.io/api/autoscaling/v2beta1"
-	v2beta2 "k8s.io/api/autoscaling/v2beta2"
 )
 
-// Handler is kubernetes hpa event handler.
-func (h *Handler) v1Add(obj interface{}, _ bool) {
-	object, ok := obj.(*v1.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v1Add type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
-		return
-	}
-
-	logger.Debug("[HpaEvent] v1Add hpa detected", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
-}
-
-// OnAdd is a method that handles the add event.
-func (h *Handler) v1Update(_, obj interface{}) {
-	object, ok := obj.(*v1.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v1Update type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	threshold, ok := h.hpaTarget[k]
-	if !ok {
-		return
-	}
-
-	if object.Status.CurrentReplicas >= object.Spec.MaxReplicas {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelCritical,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
-	} else if object.Status.CurrentReplicas >= threshold {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelWarning,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
-	}
-}
-
-// v1Delete is a method that handles the v1.HorizontalPodAutoscaler delete event.
-func (h *Handler) v1Delete(obj interface{}) {
-	object, ok := obj.(*v1.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v1Delete type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
-		return
-	}
-
-	logger.Debug("[HpaEvent] v1Delete hpa disappeared", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
-}
-
-// v2Add is a method that handles the v2.HorizontalPodAutoscaler add event.
-func (h *Handler) v2Add(obj interface{}, _ bool) {
-	object, ok := obj.(*v2.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2Add type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
-		return
-	}
-
-	logger.Debug("[HpaEvent] v2Add hpa detected", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
-}
-
-// v2Update is a method that handles the v2.HorizontalPodAutoscaler update event.
-func (h *Handler) v2Update(_, obj interface{}) {
-	object, ok := obj.(*v2.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2Update type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	threshold, ok := h.hpaTarget[k]
-	if !ok {
-		return
-	}
-
-	if object.Status.CurrentReplicas >= object.Spec.MaxReplicas {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelCritical,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
-	} else if object.Status.CurrentReplicas >= threshold {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelWarning,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
-	}
-}
-
-// v2Delete is a method that handles the v2.HorizontalPodAutoscaler delete event.
-func (h *Handler) v2Delete(obj interface{}) {
-	object, ok := obj.(*v2.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2Delete type assertion error")
+// onAdd is a method that handles the hpa add event.
+func (h *Handler) onAdd(obj interface{}, _ bool) {
+	state, err := h.convert(obj)
+	if err != nil {
+		logger.Error("[HpaEvent] onAdd convert error", zap.Error(err))
 		return
 	}
 
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
-		return
-	}
+	logger.Debug("[HpaEvent] onAdd hpa detected", zap.String("name", state.Name), zap.String("namespace", state.Namespace))
 
-	logger.Debug("[HpaEvent] v2Delete hpa disappeared", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
+	// the informer does not resync and the tracker starts empty, an hpa that is already at max fires on add
+	h.sync(state)
 }
 
-// v2beta1Add is a method that handles the v2beta1.HorizontalPodAutoscaler add event.
-func (h *Handler) v2beta1Add(obj interface{}, _ bool) {
-	object, ok := obj.(*v2beta1.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2beta1Add type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
+// onUpdate is a method that handles the hpa update event.
+func (h *Handler) onUpdate(_, obj interface{}) {
+	state, err := h.convert(obj)
+	if err != nil {
+		logger.Error("[HpaEvent] onUpdate convert error", zap.Error(err))
 		return
 	}
 
-	logger.Debug("[HpaEvent] v2beta1Add hpa detected", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
+	h.sync(state)
 }
 
-// v2beta1Update is a method that handles the v2beta1.HorizontalPodAutoscaler update event.
-func (h *Handler) v2beta1Update(_, obj interface{}) {
-	object, ok := obj.(*v2beta1.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2beta1Update type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	threshold, ok := h.hpaTarget[k]
+// sync evaluates the hpa and reports the level transitions of every alert kind
+func (h *Handler) sync(state *HPAState) {
+	t, ok := h.resolveTarget(state)
 	if !ok {
+		// the hpa may have opted out with an annotation, resolve its firing alerts
+		h.forget(state)
 		return
 	}
 
-	if object.Status.CurrentReplicas >= object.Spec.MaxReplicas {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelCritical,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
-	} else if object.Status.CurrentReplicas >= threshold {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelWarning,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
+	for _, a := range evaluate(state, t.threshold) {
+		msg := newMessage(state, a)
+		msg.Receivers = t.receivers
+		if h.tracker.observe(state.Key()+"/"+a.kind, a.level, msg) {
+			h.reporter.Report(msg)
+		}
 	}
 }
 
-// v2beta1Delete is a method that handles the v2beta1.HorizontalPodAutoscaler delete event.
-func (h *Handler) v2beta1Delete(obj interface{}) {
-	object, ok := obj.(*v2beta1.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2beta1Delete type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
+// onDelete is a method that handles the hpa delete event.
+func (h *Handler) onDelete(obj interface{}) {
+	state, err := h.convert(obj)
+	if err != nil {
+		logger.Error("[HpaEvent] onDelete convert error", zap.Error(err))
 		return
 	}
 
-	logger.Debug("[HpaEvent] v2beta1Delete hpa disappeared", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
+	// the hpa is forgotten even if it is no longer a target, it may have been one when it fired
+	h.forget(state)
 
+	logger.Debug("[HpaEvent] onDelete hpa disappeared", zap.String("name", state.Name), zap.String("namespace", state.Namespace))
 }
 
-// v2beta2Add is a method that handles v2beta2.HorizontalPodAutoscaler events
-func (h *Handler) v2beta2Add(obj interface{}, _ bool) {
-	object, ok := obj.(*v2beta2.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2beta2Add type assertion error")
+// resync evaluates every hpa of the informer cache, so a change of the targets takes effect
+// without waiting for the next update of the hpas
+func (h *Handler) resync() {
+	if h.list == nil {
 		return
 	}
 
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
-		return
+	for _, obj := range h.list() {
+		state, err := h.convert(obj)
+		if err != nil {
+			logger.Error("[HpaEvent] resync convert error", zap.Error(err))
+			continue
+		}
+		h.sync(state)
 	}
-
-	logger.Debug("[HpaEvent] v2beta2Add hpa detected", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
-
 }
 
-// v2beta2Update is a method that handles v2beta2.HorizontalPodAutoscaler events
-func (h *Handler) v2beta2Update(_, obj interface{}) {
-	object, ok := obj.(*v2beta2.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2beta2Update type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	threshold, ok := h.hpaTarget[k]
-	if !ok {
-		return
+// forget drops every alert state of the hpa and reports the alerts that were still firing as resolved
+func (h *Handler) forget(state *HPAState) {
+	for _, kind := range alertKinds {
+		if msg := h.tracker.forget(state.Key() + "/" + kind); msg != nil {
+			h.reporter.Report(msg)
+		}
 	}
-
-	if object.Status.CurrentReplicas >= object.Spec.MaxReplicas {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelCritical,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
-
-	} else if object.Status.CurrentReplicas >= threshold {
-		h.reporter.Report(&message.Data{
-			Time:            object.Status.LastScaleTime.Format("2006-01-02 15:04:05"),
-			Level:           message.LevelWarning,
-			Name:            object.Name,
-			Namespace:       object.Namespace,
-			CurrentReplicas: object.Status.CurrentReplicas,
-			MaxReplicas:     object.Spec.MaxReplicas,
-		})
-	}
-}
-
-// v2beta2Delete is a method that handles v2beta2.HorizontalPodAutoscaler events
-func (h *Handler) v2beta2Delete(obj interface{}) {
-	object, ok := obj.(*v2beta2.HorizontalPodAutoscaler)
-	if !ok {
-		logger.Error("[HpaEvent] v2beta2Delete type assertion error")
-		return
-	}
-
-	k := object.Namespace + "/" + object.Name
-	_, ok = h.hpaTarget[k]
-	if !ok {
-		return
-	}
-
-	logger.Debug("[HpaEvent] v2beta2Delete hpa disappeared", zap.String("name", object.Name), zap.String("namespace", object.Namespace))
 }
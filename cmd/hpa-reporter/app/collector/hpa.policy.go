@@ -0,0 +1,166 @@
+package collector
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/k8s"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+)
+
+const (
+	PolicyConditionReady = "Ready"
+
+	PolicyReasonAccepted = "Accepted"
+	PolicyReasonInvalid  = "Invalid"
+)
+
+// onPolicyAdd is a method that handles the HPAReportPolicy add event.
+func (h *Handler) onPolicyAdd(obj interface{}) {
+	h.reconcilePolicy(obj)
+}
+
+// onPolicyUpdate is a method that handles the HPAReportPolicy update event.
+func (h *Handler) onPolicyUpdate(_, obj interface{}) {
+	h.reconcilePolicy(obj)
+}
+
+// onPolicyDelete is a method that handles the HPAReportPolicy delete event.
+func (h *Handler) onPolicyDelete(obj interface{}) {
+	policy, err := k8s.ToPolicy(obj)
+	if err != nil {
+		logger.Error("[PolicyEvent] onPolicyDelete convert error", zap.Error(err))
+		return
+	}
+
+	h.setPolicyRules(policy.Namespace+"/"+policy.Name, nil)
+	logger.Info("[PolicyEvent] policy removed", zap.String("name", policy.Name), zap.String("namespace", policy.Namespace))
+}
+
+// reconcilePolicy compiles the policy into the target table and writes the result to its status
+func (h *Handler) reconcilePolicy(obj interface{}) {
+	policy, err := k8s.ToPolicy(obj)
+	if err != nil {
+		logger.Error("[PolicyEvent] reconcilePolicy convert error", zap.Error(err))
+		return
+	}
+
+	k := policy.Namespace + "/" + policy.Name
+	rules, err := policyRules(policy)
+	if err != nil {
+		// an invalid policy must not keep its previous rules
+		h.setPolicyRules(k, nil)
+		logger.Warn("[PolicyEvent] invalid policy", zap.String("name", policy.Name), zap.String("namespace", policy.Namespace), zap.Error(err))
+	} else {
+		h.setPolicyRules(k, rules)
+		logger.Info("[PolicyEvent] policy reconciled", zap.String("name", policy.Name), zap.String("namespace", policy.Namespace), zap.Int("rules", len(rules)))
+	}
+
+	if !setPolicyStatus(policy, err) {
+		return
+	}
+	if err = h.client.UpdatePolicyStatus(policy); err != nil {
+		logger.Warn("[PolicyEvent] failed to update policy status", zap.String("name", policy.Name), zap.String("namespace", policy.Namespace), zap.Error(err))
+	}
+}
+
+// setPolicyRules replaces the rules of the policy, swaps the target table and evaluates the hpas with it
+func (h *Handler) setPolicyRules(k string, rules []rule) {
+	h.policyMu.Lock()
+	if len(rules) == 0 {
+		delete(h.policies, k)
+	} else {
+		h.policies[k] = rules
+	}
+	h.storeTargets()
+	h.policyMu.Unlock()
+
+	h.resync()
+}
+
+// storeTargets swaps the target table with the config rules and every policy rule.
+// policyMu must be held.
+func (h *Handler) storeTargets() {
+	keys := make([]string, 0, len(h.policies))
+	for key := range h.policies {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var all []rule
+	for _, key := range keys {
+		all = append(all, h.policies[key]...)
+	}
+	h.targets.Store(h.base.with(all))
+}
+
+// policyRules compiles the policy into rules of its namespace
+func policyRules(policy *k8s.HPAReportPolicy) ([]rule, error) {
+	threshold, err := config.ParseThreshold(policy.Spec.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	if threshold.IsZero() {
+		return nil, fmt.Errorf("invalid threshold %q: must be greater than 0", policy.Spec.Threshold)
+	}
+
+	base := rule{
+		namespace: policy.Namespace,
+		threshold: threshold,
+		receivers: policy.Spec.Receivers,
+		priority:  priorityNamespace,
+	}
+	if policy.Spec.Selector != nil {
+		base.selector, err = metav1.LabelSelectorAsSelector(policy.Spec.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
+		}
+		if !base.selector.Empty() {
+			base.priority = prioritySelector
+		}
+	}
+
+	if len(policy.Spec.Names) == 0 {
+		return []rule{base}, nil
+	}
+
+	rules := make([]rule, 0, len(policy.Spec.Names))
+	for _, name := range policy.Spec.Names {
+		r := base
+		r.name = name
+		r.priority = priorityExactName
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// setPolicyStatus sets the Ready condition of the policy. It returns false if the status is unchanged.
+func setPolicyStatus(policy *k8s.HPAReportPolicy, reconcileErr error) bool {
+	condition := metav1.Condition{
+		Type:               PolicyConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             PolicyReasonAccepted,
+		Message:            "policy is applied to the target table",
+		ObservedGeneration: policy.Generation,
+	}
+	if reconcileErr != nil {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = PolicyReasonInvalid
+		condition.Message = reconcileErr.Error()
+	}
+
+	current := meta.FindStatusCondition(policy.Status.Conditions, PolicyConditionReady)
+	if policy.Status.ObservedGeneration == policy.Generation && current != nil &&
+		current.Status == condition.Status && current.Reason == condition.Reason && current.Message == condition.Message {
+		return false
+	}
+
+	policy.Status.ObservedGeneration = policy.Generation
+	meta.SetStatusCondition(&policy.Status.Conditions, condition)
+
+	return true
+}
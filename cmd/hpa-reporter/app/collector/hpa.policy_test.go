@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"errors"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"testing"
+)
+
+func newPolicy(spec k8s.HPAReportPolicySpec) *k8s.HPAReportPolicy {
+	return &k8s.HPAReportPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: "policy", Generation: 1},
+		Spec:       spec,
+	}
+}
+
+func TestPolicyTargets(t *testing.T) {
+	base, _ := newTargets([]config.HpaConfig{
+		{Namespace: "payments", Name: "api", Threshold: config.MustParseThreshold("3")},
+	}, config.HpaDefaultConfig{Enabled: true, Threshold: config.MustParseThreshold("90%")})
+	h := &Handler{base: base, policies: make(map[string][]rule)}
+	h.targets.Store(base)
+
+	rules, err := policyRules(newPolicy(k8s.HPAReportPolicySpec{
+		Selector:  &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+		Threshold: "80%",
+		Receivers: []string{"payments-slack"},
+	}))
+	if err != nil {
+		t.Fatalf("failed to compile policy: %v", err)
+	}
+	h.setPolicyRules("payments/policy", rules)
+
+	tests := []struct {
+		name      string
+		state     HPAState
+		threshold string
+		receivers []string
+	}{
+		{"config exact name wins", HPAState{Namespace: "payments", Name: "api", Labels: map[string]string{"team": "payments"}}, "3", nil},
+		{"policy selector", HPAState{Namespace: "payments", Name: "worker", Labels: map[string]string{"team": "payments"}}, "80%", []string{"payments-slack"}},
+		{"policy is namespaced", HPAState{Namespace: "orders", Name: "worker", Labels: map[string]string{"team": "payments"}}, "90%", nil},
+		{"selector does not match", HPAState{Namespace: "payments", Name: "worker"}, "90%", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := h.targets.Load().match(&tt.state)
+			if !ok {
+				t.Fatal("expected a rule to match")
+			}
+			if target.threshold.String() != tt.threshold {
+				t.Errorf("expected threshold %s, got %s", tt.threshold, target.threshold)
+			}
+			if !reflect.DeepEqual(target.receivers, tt.receivers) {
+				t.Errorf("expected receivers %v, got %v", tt.receivers, target.receivers)
+			}
+		})
+	}
+
+	// removing the policy restores the config rules
+	h.setPolicyRules("payments/policy", nil)
+	if target, _ := h.targets.Load().match(&HPAState{Namespace: "payments", Name: "worker", Labels: map[string]string{"team": "payments"}}); target.threshold.String() != "90%" {
+		t.Errorf("expected default threshold after policy removal, got %s", target.threshold)
+	}
+}
+
+func TestPolicyRulesNames(t *testing.T) {
+	rules, err := policyRules(newPolicy(k8s.HPAReportPolicySpec{Names: []string{"a", "b"}, Threshold: "max-1"}))
+	if err != nil {
+		t.Fatalf("failed to compile policy: %v", err)
+	}
+	if len(rules) != 2 || rules[0].name != "a" || rules[1].name != "b" || rules[0].priority != priorityExactName {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	if _, err = policyRules(newPolicy(k8s.HPAReportPolicySpec{Threshold: "lots"})); err == nil {
+		t.Error("expected error for invalid threshold")
+	}
+	if _, err = policyRules(newPolicy(k8s.HPAReportPolicySpec{Threshold: "0"})); err == nil {
+		t.Error("expected error for zero threshold")
+	}
+}
+
+func TestSetPolicyStatus(t *testing.T) {
+	policy := newPolicy(k8s.HPAReportPolicySpec{Threshold: "80%"})
+
+	if !setPolicyStatus(policy, nil) {
+		t.Fatal("expected status to change")
+	}
+	if policy.Status.ObservedGeneration != 1 || policy.Status.Conditions[0].Status != metav1.ConditionTrue {
+		t.Errorf("unexpected status: %+v", policy.Status)
+	}
+	if setPolicyStatus(policy, nil) {
+		t.Error("expected unchanged status not to be written")
+	}
+
+	if !setPolicyStatus(policy, errors.New("invalid threshold")) {
+		t.Fatal("expected status to change")
+	}
+	if c := policy.Status.Conditions[0]; c.Status != metav1.ConditionFalse || c.Reason != PolicyReasonInvalid {
+		t.Errorf("unexpected condition: %+v", c)
+	}
+}
@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"time"
+)
+
+const (
+	TimeFormat = "2006-01-02 15:04:05"
+)
+
+type (
+	// HPAState is a version agnostic view of a HorizontalPodAutoscaler.
+	// every supported autoscaling api version is converted into it before evaluation.
+	HPAState struct {
+		Namespace   string
+		Name        string
+		Labels      map[string]string
+		Annotations map[string]string
+
+		Target     Target
+		Spec       Spec
+		Status     Status
+		Conditions []Condition
+		Metrics    []Metric
+	}
+
+	// Target is the workload scaled by the hpa
+	Target struct {
+		APIVersion string
+		Kind       string
+		Name       string
+	}
+
+	// Spec is the replica bounds of the hpa
+	Spec struct {
+		MinReplicas int32
+		MaxReplicas int32
+	}
+
+	// Status is the observed replica state of the hpa
+	Status struct {
+		CurrentReplicas int32
+		DesiredReplicas int32
+		LastScaleTime   *time.Time
+	}
+
+	// Condition is an hpa status condition (AbleToScale, ScalingActive, ScalingLimited)
+	Condition struct {
+		Type               string
+		Status             string
+		Reason             string
+		Message            string
+		LastTransitionTime time.Time
+	}
+
+	// Metric is a scaling metric with its target and current value
+	Metric struct {
+		Type    string
+		Name    string
+		Target  string
+		Current string
+	}
+)
+
+// Key returns namespace/name of the hpa
+func (s *HPAState) Key() string {
+	return s.Namespace + "/" + s.Name
+}
+
+// Condition returns the condition of the given type
+func (s *HPAState) Condition(conditionType string) (Condition, bool) {
+	for _, c := range s.Conditions {
+		if c.Type == conditionType {
+			return c, true
+		}
+	}
+
+	return Condition{}, false
+}
+
+// newMessage creates a message from the hpa state
+func newMessage(s *HPAState, a alert) *message.Data {
+	msg := &message.Data{
+		Level:           a.level,
+		Alert:           a.kind,
+		Name:            s.Name,
+		Namespace:       s.Namespace,
+		Labels:          s.Labels,
+		Annotations:     s.Annotations,
+		CurrentReplicas: s.Status.CurrentReplicas,
+		DesiredReplicas: s.Status.DesiredReplicas,
+		MaxReplicas:     s.Spec.MaxReplicas,
+		TargetKind:      s.Target.Kind,
+		TargetName:      s.Target.Name,
+	}
+	if s.Status.LastScaleTime != nil {
+		msg.Time = s.Status.LastScaleTime.Format(TimeFormat)
+	}
+	if a.condition != nil {
+		msg.ConditionReason = a.condition.Reason
+		msg.ConditionMessage = a.condition.Message
+	}
+
+	return msg
+}
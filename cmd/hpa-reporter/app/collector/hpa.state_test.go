@@ -0,0 +1,313 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	v1 "k8s.io/api/autoscaling/v1"
+	v2 "k8s.io/api/autoscaling/v2"
+	v2beta1 "k8s.io/api/autoscaling/v2beta1"
+	v2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+type fakeReporter struct {
+	messages []*message.Data
+}
+
+func (f *fakeReporter) Report(msg *message.Data) {
+	f.messages = append(f.messages, msg)
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
+
+func newV1(current int32) interface{} {
+	return &v1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "my-hpa", Annotations: map[string]string{
+			AnnotationV1Conditions: `[{"type":"ScalingLimited","status":"True","reason":"TooManyReplicas"}]`,
+		}},
+		Spec: v1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef:                 v1.CrossVersionObjectReference{Kind: "Deployment", Name: "my-app"},
+			MaxReplicas:                    10,
+			TargetCPUUtilizationPercentage: int32Ptr(80),
+		},
+		Status: v1.HorizontalPodAutoscalerStatus{CurrentReplicas: current, CurrentCPUUtilizationPercentage: int32Ptr(95)},
+	}
+}
+
+func newV2(current int32) interface{} {
+	return &v2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "my-hpa"},
+		Spec: v2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: v2.CrossVersionObjectReference{Kind: "Deployment", Name: "my-app"},
+			MaxReplicas:    10,
+			Metrics: []v2.MetricSpec{{Type: v2.ResourceMetricSourceType, Resource: &v2.ResourceMetricSource{
+				Name: corev1.ResourceCPU, Target: v2.MetricTarget{Type: v2.UtilizationMetricType, AverageUtilization: int32Ptr(80)},
+			}}},
+		},
+		Status: v2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: current,
+			CurrentMetrics: []v2.MetricStatus{{Type: v2.ResourceMetricSourceType, Resource: &v2.ResourceMetricStatus{
+				Name: corev1.ResourceCPU, Current: v2.MetricValueStatus{AverageUtilization: int32Ptr(95)},
+			}}},
+			Conditions: []v2.HorizontalPodAutoscalerCondition{{Type: v2.ScalingLimited, Status: corev1.ConditionTrue, Reason: "TooManyReplicas"}},
+		},
+	}
+}
+
+func newV2beta1(current int32) interface{} {
+	return &v2beta1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "my-hpa"},
+		Spec: v2beta1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: v2beta1.CrossVersionObjectReference{Kind: "Deployment", Name: "my-app"},
+			MaxReplicas:    10,
+			Metrics: []v2beta1.MetricSpec{{Type: v2beta1.ResourceMetricSourceType, Resource: &v2beta1.ResourceMetricSource{
+				Name: corev1.ResourceCPU, TargetAverageUtilization: int32Ptr(80),
+			}}},
+		},
+		Status: v2beta1.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: current,
+			CurrentMetrics: []v2beta1.MetricStatus{{Type: v2beta1.ResourceMetricSourceType, Resource: &v2beta1.ResourceMetricStatus{
+				Name: corev1.ResourceCPU, CurrentAverageUtilization: int32Ptr(95),
+			}}},
+			Conditions: []v2beta1.HorizontalPodAutoscalerCondition{{Type: v2beta1.ScalingLimited, Status: corev1.ConditionTrue, Reason: "TooManyReplicas"}},
+		},
+	}
+}
+
+func newV2beta2(current int32) interface{} {
+	return &v2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "my-hpa"},
+		Spec: v2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: v2beta2.CrossVersionObjectReference{Kind: "Deployment", Name: "my-app"},
+			MaxReplicas:    10,
+			Metrics: []v2beta2.MetricSpec{{Type: v2beta2.ResourceMetricSourceType, Resource: &v2beta2.ResourceMetricSource{
+				Name: corev1.ResourceCPU, Target: v2beta2.MetricTarget{Type: v2beta2.UtilizationMetricType, AverageUtilization: int32Ptr(80)},
+			}}},
+		},
+		Status: v2beta2.HorizontalPodAutoscalerStatus{
+			CurrentReplicas: current,
+			CurrentMetrics: []v2beta2.MetricStatus{{Type: v2beta2.ResourceMetricSourceType, Resource: &v2beta2.ResourceMetricStatus{
+				Name: corev1.ResourceCPU, Current: v2beta2.MetricValueStatus{AverageUtilization: int32Ptr(95)},
+			}}},
+			Conditions: []v2beta2.HorizontalPodAutoscalerCondition{{Type: v2beta2.ScalingLimited, Status: corev1.ConditionTrue, Reason: "TooManyReplicas"}},
+		},
+	}
+}
+
+var versions = []struct {
+	version string
+	newHPA  func(current int32) interface{}
+}{
+	{"v1", newV1},
+	{"v2", newV2},
+	{"v2beta1", newV2beta1},
+	{"v2beta2", newV2beta2},
+}
+
+func TestConvert(t *testing.T) {
+	for _, v := range versions {
+		t.Run(v.version, func(t *testing.T) {
+			convert, err := converterFor(v.version)
+			if err != nil {
+				t.Fatalf("failed to get converter: %v", err)
+			}
+
+			state, err := convert(v.newHPA(4))
+			if err != nil {
+				t.Fatalf("failed to convert: %v", err)
+			}
+
+			if state.Key() != "test/my-hpa" {
+				t.Errorf("unexpected key: %s", state.Key())
+			}
+			if state.Spec.MinReplicas != 1 || state.Spec.MaxReplicas != 10 || state.Status.CurrentReplicas != 4 {
+				t.Errorf("unexpected replicas: %+v %+v", state.Spec, state.Status)
+			}
+			if state.Target.Kind != "Deployment" || state.Target.Name != "my-app" {
+				t.Errorf("unexpected target: %+v", state.Target)
+			}
+			if c, ok := state.Condition("ScalingLimited"); !ok || c.Reason != "TooManyReplicas" {
+				t.Errorf("unexpected conditions: %+v", state.Conditions)
+			}
+			if len(state.Metrics) != 1 || state.Metrics[0].Name != "cpu" || state.Metrics[0].Target != "80%" || state.Metrics[0].Current != "95%" {
+				t.Errorf("unexpected metrics: %+v", state.Metrics)
+			}
+		})
+	}
+}
+
+func TestConvertUnexpectedType(t *testing.T) {
+	if _, err := convertV2(newV1(1)); err == nil {
+		t.Error("expected error for unexpected object type")
+	}
+}
+
+func TestOnUpdate(t *testing.T) {
+	tests := []struct {
+		name    string
+		current int32
+		want    string
+	}{
+		{"below threshold", 6, ""},
+		{"threshold", 7, message.LevelWarning},
+		{"max replicas", 10, message.LevelCritical},
+	}
+
+	for _, v := range versions {
+		for _, tt := range tests {
+			t.Run(v.version+"/"+tt.name, func(t *testing.T) {
+				convert, _ := converterFor(v.version)
+				reporter := &fakeReporter{}
+				targets, _ := newTargets([]config.HpaConfig{
+					{Namespace: "test", Name: "my-hpa", Threshold: config.MustParseThreshold("7")},
+				}, config.HpaDefaultConfig{})
+				h := &Handler{
+					reporter: reporter,
+					convert:  convert,
+					tracker:  newTracker(0),
+				}
+				h.targets.Store(targets)
+
+				h.onUpdate(nil, v.newHPA(tt.current))
+
+				var messages []*message.Data
+				for _, msg := range reporter.messages {
+					if msg.Alert == message.AlertReplicas {
+						messages = append(messages, msg)
+					}
+				}
+
+				if tt.want == "" {
+					if len(messages) != 0 {
+						t.Errorf("expected no message, got %+v", messages[0])
+					}
+					return
+				}
+				if len(messages) != 1 {
+					t.Fatalf("expected 1 message, got %d", len(messages))
+				}
+				if msg := messages[0]; msg.Level != tt.want || msg.CurrentReplicas != tt.current || msg.MaxReplicas != 10 {
+					t.Errorf("unexpected message: %+v", msg)
+				}
+			})
+		}
+	}
+}
+
+func TestOnAdd(t *testing.T) {
+	convert, _ := converterFor(versions[1].version)
+	targets, _ := newTargets([]config.HpaConfig{
+		{Namespace: "test", Name: "my-hpa", Threshold: config.MustParseThreshold("7")},
+	}, config.HpaDefaultConfig{})
+
+	reporter := &fakeReporter{}
+	h := &Handler{reporter: reporter, convert: convert, tracker: newTracker(0)}
+	h.targets.Store(targets)
+
+	// an hpa that is already at max when the informer lists it fires without an update
+	h.onAdd(versions[1].newHPA(10), true)
+	if len(reporter.messages) == 0 || reporter.messages[0].Level != message.LevelCritical {
+		t.Fatalf("expected critical message on add, got %+v", reporter.messages)
+	}
+
+	firing := len(reporter.messages)
+	h.onUpdate(nil, versions[1].newHPA(10))
+	if len(reporter.messages) != firing {
+		t.Errorf("expected the update to be tracked as the same level, got %+v", reporter.messages[firing:])
+	}
+}
+
+func TestResync(t *testing.T) {
+	convert, _ := converterFor(versions[1].version)
+	targets, _ := newTargets(nil, config.HpaDefaultConfig{})
+
+	reporter := &fakeReporter{}
+	h := &Handler{
+		reporter: reporter,
+		convert:  convert,
+		list:     func() []interface{} { return []interface{}{versions[1].newHPA(10)} },
+		tracker:  newTracker(0),
+		policies: make(map[string][]rule),
+	}
+	h.base = targets
+	h.targets.Store(targets)
+
+	h.onAdd(versions[1].newHPA(10), true)
+	if len(reporter.messages) != 0 {
+		t.Fatalf("expected no message for an hpa that is not a target, got %+v", reporter.messages)
+	}
+
+	// a policy that targets the hpa evaluates it right away
+	h.setPolicyRules("test/policy", []rule{{namespace: "test", threshold: config.MustParseThreshold("7"), priority: priorityNamespace}})
+	if len(reporter.messages) == 0 || reporter.messages[0].Level != message.LevelCritical {
+		t.Fatalf("expected critical message after the policy was applied, got %+v", reporter.messages)
+	}
+
+	// and resolves its alerts when the policy is removed
+	firing := len(reporter.messages)
+	h.setPolicyRules("test/policy", nil)
+	for _, msg := range reporter.messages[firing:] {
+		if msg.Level != message.LevelResolved {
+			t.Errorf("expected resolved message, got %+v", msg)
+		}
+	}
+	if len(reporter.messages) != 2*firing {
+		t.Errorf("expected %d resolved messages, got %d", firing, len(reporter.messages)-firing)
+	}
+}
+
+func TestForgetResolvesFiringAlerts(t *testing.T) {
+	convert, _ := converterFor(versions[0].version)
+	targets, _ := newTargets([]config.HpaConfig{
+		{Namespace: "test", Name: "my-hpa", Threshold: config.MustParseThreshold("7")},
+	}, config.HpaDefaultConfig{})
+
+	optOut := func(obj interface{}) interface{} {
+		hpa := obj.(*v1.HorizontalPodAutoscaler)
+		hpa.Annotations[AnnotationEnabled] = "false"
+		return hpa
+	}
+
+	tests := []struct {
+		name   string
+		remove func(h *Handler)
+	}{
+		{"deleted", func(h *Handler) { h.onDelete(versions[0].newHPA(10)) }},
+		{"opted out", func(h *Handler) { h.onUpdate(nil, optOut(versions[0].newHPA(10))) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &fakeReporter{}
+			h := &Handler{reporter: reporter, convert: convert, tracker: newTracker(0)}
+			h.targets.Store(targets)
+
+			h.onUpdate(nil, versions[0].newHPA(10))
+			firing := len(reporter.messages)
+			if firing == 0 {
+				t.Fatal("expected firing alerts")
+			}
+
+			reporter.messages = nil
+			tt.remove(h)
+			if len(reporter.messages) != firing {
+				t.Fatalf("expected %d resolved messages, got %d", firing, len(reporter.messages))
+			}
+			for _, msg := range reporter.messages {
+				if msg.Level != message.LevelResolved || msg.FiringLevel == "" || msg.Name != "my-hpa" {
+					t.Errorf("unexpected resolved message: %+v", msg)
+				}
+			}
+
+			reporter.messages = nil
+			tt.remove(h)
+			if len(reporter.messages) != 0 {
+				t.Errorf("expected the alerts to be resolved once, got %+v", reporter.messages)
+			}
+		})
+	}
+}
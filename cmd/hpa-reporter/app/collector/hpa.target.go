@@ -0,0 +1,155 @@
+package collector
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"k8s.io/apimachinery/pkg/labels"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// rule priorities, a lower value wins when several rules match the same hpa
+const (
+	priorityExactName = iota
+	priorityNameRegex
+	prioritySelector
+	priorityNamespace
+	priorityDefault
+)
+
+// rule is a compiled hpa target rule
+type rule struct {
+	namespace string
+	name      string
+	nameRegex *regexp.Regexp
+	selector  labels.Selector
+	threshold config.Threshold
+	receivers []string
+	priority  int
+}
+
+// match reports whether the hpa satisfies every criteria of the rule
+func (r *rule) match(s *HPAState) bool {
+	if r.namespace != "" {
+		if ok, _ := path.Match(r.namespace, s.Namespace); !ok {
+			return false
+		}
+	}
+	if r.name != "" && r.name != s.Name {
+		return false
+	}
+	if r.nameRegex != nil && !r.nameRegex.MatchString(s.Name) {
+		return false
+	}
+	if r.selector != nil && !r.selector.Matches(labels.Set(s.Labels)) {
+		return false
+	}
+
+	return true
+}
+
+// target is the resolved alert setting of an hpa
+type target struct {
+	threshold config.Threshold
+	// receivers limits the reporters that get the alert, empty means every reporter
+	receivers []string
+}
+
+// targets resolves the threshold of an hpa from the configured rules.
+// the most specific rule wins: exact name > name regex > label selector > namespace > default.
+// rules of the same priority are evaluated in config order.
+type targets struct {
+	rules []rule
+}
+
+// newTargets compiles the hpa configs into targets
+func newTargets(configs []config.HpaConfig, def config.HpaDefaultConfig) (*targets, error) {
+	t := &targets{}
+
+	for i, cfg := range configs {
+		r, err := newRule(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("hpa[%d]: %w", i, err)
+		}
+		t.rules = append(t.rules, r)
+	}
+
+	if def.Enabled {
+		t.rules = append(t.rules, rule{threshold: def.Threshold, priority: priorityDefault})
+	}
+
+	sort.SliceStable(t.rules, func(i, j int) bool {
+		return t.rules[i].priority < t.rules[j].priority
+	})
+
+	return t, nil
+}
+
+// with returns a copy of the targets with the additional rules.
+// the additional rules are evaluated after the existing rules of the same priority.
+func (t *targets) with(rules []rule) *targets {
+	result := &targets{rules: make([]rule, 0, len(t.rules)+len(rules))}
+	result.rules = append(result.rules, t.rules...)
+	result.rules = append(result.rules, rules...)
+
+	sort.SliceStable(result.rules, func(i, j int) bool {
+		return result.rules[i].priority < result.rules[j].priority
+	})
+
+	return result
+}
+
+// newRule compiles a single hpa config
+func newRule(cfg config.HpaConfig) (rule, error) {
+	r := rule{
+		namespace: cfg.Namespace,
+		name:      cfg.Name,
+		threshold: cfg.Threshold,
+	}
+
+	if _, err := path.Match(cfg.Namespace, ""); err != nil {
+		return rule{}, fmt.Errorf("invalid namespace glob %q: %w", cfg.Namespace, err)
+	}
+
+	if cfg.NameRegex != "" {
+		re, err := regexp.Compile(cfg.NameRegex)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid nameRegex %q: %w", cfg.NameRegex, err)
+		}
+		r.nameRegex = re
+	}
+
+	if strings.TrimSpace(cfg.Selector) != "" {
+		selector, err := labels.Parse(cfg.Selector)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid selector %q: %w", cfg.Selector, err)
+		}
+		r.selector = selector
+	}
+
+	switch {
+	case r.name != "":
+		r.priority = priorityExactName
+	case r.nameRegex != nil:
+		r.priority = priorityNameRegex
+	case r.selector != nil:
+		r.priority = prioritySelector
+	default:
+		r.priority = priorityNamespace
+	}
+
+	return r, nil
+}
+
+// match returns the target of the most specific rule that matches the hpa
+func (t *targets) match(s *HPAState) (target, bool) {
+	for i := range t.rules {
+		if t.rules[i].match(s) {
+			return target{threshold: t.rules[i].threshold, receivers: t.rules[i].receivers}, true
+		}
+	}
+
+	return target{}, false
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"testing"
+)
+
+func TestTargetsMatch(t *testing.T) {
+	targets, err := newTargets([]config.HpaConfig{
+		{Namespace: "team-*", Threshold: config.MustParseThreshold("1")},
+		{Selector: "app=web,tier in (frontend)", Threshold: config.MustParseThreshold("2")},
+		{Namespace: "team-*", NameRegex: "^api-", Threshold: config.MustParseThreshold("3")},
+		{Namespace: "team-a", Name: "api-server", Threshold: config.MustParseThreshold("4")},
+	}, config.HpaDefaultConfig{Enabled: true, Threshold: config.MustParseThreshold("80%")})
+	if err != nil {
+		t.Fatalf("failed to create targets: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		state     HPAState
+		threshold string
+	}{
+		{"exact name wins", HPAState{Namespace: "team-a", Name: "api-server", Labels: map[string]string{"app": "web", "tier": "frontend"}}, "4"},
+		{"name regex wins over selector", HPAState{Namespace: "team-b", Name: "api-server", Labels: map[string]string{"app": "web", "tier": "frontend"}}, "3"},
+		{"selector wins over namespace", HPAState{Namespace: "team-b", Name: "web", Labels: map[string]string{"app": "web", "tier": "frontend"}}, "2"},
+		{"namespace glob", HPAState{Namespace: "team-b", Name: "worker"}, "1"},
+		{"default rule", HPAState{Namespace: "default", Name: "worker"}, "80%"},
+		{"selector in other namespace", HPAState{Namespace: "default", Name: "web", Labels: map[string]string{"app": "web", "tier": "frontend"}}, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, ok := targets.match(&tt.state)
+			if !ok {
+				t.Fatal("expected a rule to match")
+			}
+			if target.threshold.String() != tt.threshold {
+				t.Errorf("expected threshold %s, got %s", tt.threshold, target.threshold)
+			}
+		})
+	}
+}
+
+func TestTargetsNoDefault(t *testing.T) {
+	targets, err := newTargets([]config.HpaConfig{
+		{Namespace: "test", Name: "my-hpa", Threshold: config.MustParseThreshold("3")},
+	}, config.HpaDefaultConfig{})
+	if err != nil {
+		t.Fatalf("failed to create targets: %v", err)
+	}
+
+	if _, ok := targets.match(&HPAState{Namespace: "test", Name: "other"}); ok {
+		t.Error("expected no rule to match")
+	}
+}
+
+func TestNewTargetsInvalid(t *testing.T) {
+	tests := []config.HpaConfig{
+		{NameRegex: "api-("},
+		{Selector: "app in (web"},
+		{Namespace: "team-["},
+	}
+
+	for _, cfg := range tests {
+		if _, err := newTargets([]config.HpaConfig{cfg}, config.HpaDefaultConfig{}); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
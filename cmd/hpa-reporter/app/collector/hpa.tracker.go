@@ -0,0 +1,126 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"sync"
+	"time"
+)
+
+const (
+	renotifyCheckPeriod = 10 * time.Second
+)
+
+// alertState is the last notified state of an hpa
+type alertState struct {
+	level    string
+	since    time.Time
+	notified time.Time
+	last     *message.Data
+}
+
+// tracker keeps the alert state of each hpa so that only level transitions are notified.
+// OK -> Warning -> Critical -> OK(resolved), a firing hpa is notified again after renotifyInterval.
+type tracker struct {
+	mu               sync.Mutex
+	renotifyInterval time.Duration
+	states           map[string]*alertState
+	now              func() time.Time
+}
+
+// newTracker creates a new tracker
+func newTracker(renotifyInterval time.Duration) *tracker {
+	return &tracker{
+		renotifyInterval: renotifyInterval,
+		states:           make(map[string]*alertState),
+		now:              time.Now,
+	}
+}
+
+// observe records the evaluated level of the hpa and returns true if msg should be notified.
+// an empty level means the hpa is OK. when a firing hpa becomes OK, msg is turned into a resolved message.
+func (t *tracker) observe(key, level string, msg *message.Data) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	state, ok := t.states[key]
+
+	if level == "" {
+		if !ok {
+			return false
+		}
+		delete(t.states, key)
+
+		msg.Level = message.LevelResolved
+		msg.Duration = now.Sub(state.since)
+		msg.FiringLevel = state.level
+		return true
+	}
+
+	if !ok {
+		t.states[key] = &alertState{level: level, since: now, notified: now, last: msg}
+		return true
+	}
+
+	state.last = msg
+	if state.level != level {
+		state.level = level
+		state.notified = now
+		return true
+	}
+
+	if t.renotifyInterval > 0 && now.Sub(state.notified) >= t.renotifyInterval {
+		state.notified = now
+		return true
+	}
+
+	return false
+}
+
+// setRenotifyInterval changes the renotify interval of the firing alerts
+func (t *tracker) setRenotifyInterval(renotifyInterval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.renotifyInterval = renotifyInterval
+}
+
+// due returns the last message of every firing hpa that was not notified within renotifyInterval
+func (t *tracker) due() []*message.Data {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.renotifyInterval <= 0 {
+		return nil
+	}
+
+	now := t.now()
+	var result []*message.Data
+	for _, state := range t.states {
+		if now.Sub(state.notified) >= t.renotifyInterval {
+			state.notified = now
+			result = append(result, state.last)
+		}
+	}
+
+	return result
+}
+
+// forget removes the state of a deleted or opted out hpa and returns the resolved message
+// of the alert, nil if it was not firing. without it the reporters keep the alert open.
+func (t *tracker) forget(key string) *message.Data {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.states[key]
+	if !ok {
+		return nil
+	}
+	delete(t.states, key)
+
+	msg := *state.last
+	msg.Level = message.LevelResolved
+	msg.Duration = t.now().Sub(state.since)
+	msg.FiringLevel = state.level
+	return &msg
+}
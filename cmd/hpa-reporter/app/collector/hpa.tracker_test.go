@@ -0,0 +1,87 @@
+package collector
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"testing"
+	"time"
+)
+
+func TestTrackerObserve(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := newTracker(10 * time.Minute)
+	tr.now = func() time.Time { return now }
+
+	steps := []struct {
+		name    string
+		elapsed time.Duration
+		level   string
+		want    bool
+	}{
+		{"ok is not notified", 0, "", false},
+		{"ok to warning", time.Minute, message.LevelWarning, true},
+		{"warning resync", time.Minute, message.LevelWarning, false},
+		{"warning to critical", time.Minute, message.LevelCritical, true},
+		{"critical resync", 5 * time.Minute, message.LevelCritical, false},
+		{"critical after renotify interval", 5 * time.Minute, message.LevelCritical, true},
+		{"critical to warning", time.Minute, message.LevelWarning, true},
+		{"warning to ok", time.Minute, "", true},
+		{"ok resync", time.Minute, "", false},
+		{"ok to warning again", time.Minute, message.LevelWarning, true},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.elapsed)
+		if got := tr.observe("test/my-hpa", step.level, &message.Data{Level: step.level}); got != step.want {
+			t.Errorf("%s: observe() = %v, want %v", step.name, got, step.want)
+		}
+	}
+}
+
+func TestTrackerDue(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := newTracker(10 * time.Minute)
+	tr.now = func() time.Time { return now }
+
+	tr.observe("test/a", message.LevelWarning, &message.Data{Name: "a"})
+	now = now.Add(5 * time.Minute)
+	tr.observe("test/b", message.LevelCritical, &message.Data{Name: "b"})
+
+	now = now.Add(5 * time.Minute)
+	due := tr.due()
+	if len(due) != 1 || due[0].Name != "a" {
+		t.Fatalf("expected only a to be due, got %+v", due)
+	}
+	if due = tr.due(); len(due) != 0 {
+		t.Errorf("expected nothing due right after re-notification, got %+v", due)
+	}
+
+	if msg := tr.forget("test/b"); msg == nil || msg.Level != message.LevelResolved || msg.FiringLevel != message.LevelCritical || msg.Name != "b" {
+		t.Errorf("expected forgotten firing hpa to be resolved, got %+v", msg)
+	}
+	if msg := tr.forget("test/b"); msg != nil {
+		t.Errorf("expected nothing to resolve for an unknown hpa, got %+v", msg)
+	}
+	now = now.Add(10 * time.Minute)
+	if due = tr.due(); len(due) != 1 || due[0].Name != "a" {
+		t.Errorf("expected forgotten hpa not to be due, got %+v", due)
+	}
+}
+
+func TestTrackerResolved(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := newTracker(0)
+	tr.now = func() time.Time { return now }
+
+	tr.observe("test/my-hpa", message.LevelWarning, &message.Data{})
+	now = now.Add(3 * time.Minute)
+	tr.observe("test/my-hpa", message.LevelCritical, &message.Data{})
+	now = now.Add(2 * time.Minute)
+
+	msg := &message.Data{}
+	if !tr.observe("test/my-hpa", "", msg) {
+		t.Fatal("expected resolved message to be notified")
+	}
+	if msg.Level != message.LevelResolved || msg.Duration != 5*time.Minute || msg.FiringLevel != message.LevelCritical {
+		t.Errorf("unexpected resolved message: %+v", msg)
+	}
+}
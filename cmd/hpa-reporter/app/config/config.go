@@ -1,50 +1,196 @@
 package config
 
 import (
-	"gopkg.in/yaml.v2"
 	"os"
+	"time"
 )
 
 const (
 	DefaultConfigPath = "/etc/hpa-reporter/config.yaml"
+
+	OverflowDropOldest = "dropOldest"
+	OverflowDropNewest = "dropNewest"
+	OverflowBlock      = "block"
+
+	DefaultQueueSize    = 100
+	DefaultBlockTimeout = 5 * time.Second
+
+	DefaultMaxAttempts    = 5
+	DefaultInitialBackoff = 500 * time.Millisecond
+	DefaultMaxBackoff     = 30 * time.Second
+
+	DefaultSpoolRetryInterval = 5 * time.Minute
+	DefaultSpoolMaxAge        = 24 * time.Hour
 )
 
 type (
+	// Reporter is a reporter instance. Configs values may reference ${env:NAME} or ${file:/path},
+	// and SecretRefs sets a config key from a kubernetes secret.
 	Reporter struct {
-		Name    string            `yaml:"name"`
-		Configs map[string]string `yaml:"configs"`
+		Name       string               `yaml:"name"`
+		Type       string               `yaml:"type"`
+		Configs    map[string]string    `yaml:"configs"`
+		SecretRefs map[string]SecretRef `yaml:"secretRefs"`
+		Queue      QueueConfig          `yaml:"queue"`
+		Retry      RetryConfig          `yaml:"retry"`
+	}
+
+	// QueueConfig bounds the messages waiting for a reporter.
+	// Overflow is dropOldest (default), dropNewest or block, which waits up to BlockTimeout for a free slot.
+	QueueConfig struct {
+		Size         int           `yaml:"size"`
+		Overflow     string        `yaml:"overflow"`
+		BlockTimeout time.Duration `yaml:"blockTimeout"`
 	}
 
-	ReporterConfig struct {
-		Slack  []Reporter `yaml:"slack"`
-		Stdout []Reporter `yaml:"stdout"`
+	// RetryConfig retries a delivery that failed with a network error, 5xx or 429.
+	// MaxAttempts counts the first attempt, 1 disables retries. Retry-After of the endpoint is honoured.
+	RetryConfig struct {
+		MaxAttempts    int           `yaml:"maxAttempts"`
+		InitialBackoff time.Duration `yaml:"initialBackoff"`
+		MaxBackoff     time.Duration `yaml:"maxBackoff"`
+	}
+
+	// SecretRef is a key of a kubernetes secret. Namespace defaults to the namespace of the reporter pod.
+	SecretRef struct {
+		Namespace string `yaml:"namespace"`
+		Name      string `yaml:"name"`
+		Key       string `yaml:"key"`
 	}
 )
 
 type (
+	// HpaConfig selects hpa by namespace, name and labels. Every field that is set has to match.
 	HpaConfig struct {
-		Name      string `yaml:"name"`
+		// Name is the exact hpa name
+		Name string `yaml:"name"`
+		// NameRegex is a regular expression the hpa name has to match
+		NameRegex string `yaml:"nameRegex"`
+		// Namespace is the exact namespace or a glob such as "team-*"
+		Namespace string `yaml:"namespace"`
+		// Selector is a kubernetes label selector such as "app=web,tier in (frontend)"
+		Selector  string    `yaml:"selector"`
+		Threshold Threshold `yaml:"threshold"`
+	}
+
+	// HpaDefaultConfig is the rule for every hpa in the cluster that no other rule matches
+	HpaDefaultConfig struct {
+		Enabled   bool      `yaml:"enabled"`
+		Threshold Threshold `yaml:"threshold"`
+	}
+)
+
+type (
+	// AlertConfig controls how often a firing hpa is notified
+	AlertConfig struct {
+		// RenotifyInterval re-sends a still firing alert after the interval. 0 disables re-notification.
+		RenotifyInterval time.Duration `yaml:"renotifyInterval"`
+	}
+)
+
+type (
+	// SpoolConfig keeps the messages that a reporter failed to deliver after its retries.
+	// an empty Path disables the spool. the spool is opened at start, changes need a restart.
+	SpoolConfig struct {
+		Path string `yaml:"path"`
+		// RetryInterval is how often the spooled messages are delivered again
+		RetryInterval time.Duration `yaml:"retryInterval"`
+		// MaxAge removes the messages that could not be delivered for this long
+		MaxAge time.Duration `yaml:"maxAge"`
+	}
+)
+
+type (
+	// Route sends the messages it matches to its receivers, or to the receivers of the first
+	// matching child route. a child without receivers uses the receivers of its parent.
+	// Continue keeps looking for matching siblings after the route matched.
+	Route struct {
+		Match     RouteMatch `yaml:"match"`
+		Receivers []string   `yaml:"receivers"`
+		Continue  bool       `yaml:"continue"`
+		Routes    []Route    `yaml:"routes"`
+	}
+
+	// RouteMatch selects messages, every field that is set has to match
+	RouteMatch struct {
+		// Namespace is the exact namespace or a glob such as "team-*"
 		Namespace string `yaml:"namespace"`
-		Threshold int32  `yaml:"threshold"`
+		// Name is the exact hpa name
+		Name string `yaml:"name"`
+		// NameRegex is a regular expression the hpa name has to match
+		NameRegex string `yaml:"nameRegex"`
+		// Selector is a kubernetes label selector on the hpa labels
+		Selector string `yaml:"selector"`
+		// Annotations have to be set on the hpa with the exact values
+		Annotations map[string]string `yaml:"annotations"`
+		// Levels are warning, critical or resolved. a resolved message also matches the level it had while firing.
+		Levels []string `yaml:"levels"`
 	}
 )
 
 type AppConfig struct {
-	Reporters ReporterConfig `yaml:"reporters"`
-	Hpa       []HpaConfig    `yaml:"hpa"`
+	Reporters  []Reporter       `yaml:"reporters"`
+	Route      *Route           `yaml:"route"`
+	Hpa        []HpaConfig      `yaml:"hpa"`
+	HpaDefault HpaDefaultConfig `yaml:"hpaDefault"`
+	Alert      AlertConfig      `yaml:"alert"`
+	Spool      SpoolConfig      `yaml:"spool"`
+}
+
+// WithDefaults returns the spool config with the defaults for the unset fields
+func (s SpoolConfig) WithDefaults() SpoolConfig {
+	if s.RetryInterval == 0 {
+		s.RetryInterval = DefaultSpoolRetryInterval
+	}
+	if s.MaxAge == 0 {
+		s.MaxAge = DefaultSpoolMaxAge
+	}
+
+	return s
 }
 
-// LoadConfig reads the configuration file and returns the AppConfig object
+// WithDefaults returns the retry config with the defaults for the unset fields
+func (r RetryConfig) WithDefaults() RetryConfig {
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = DefaultMaxAttempts
+	}
+	if r.InitialBackoff == 0 {
+		r.InitialBackoff = DefaultInitialBackoff
+	}
+	if r.MaxBackoff == 0 {
+		r.MaxBackoff = DefaultMaxBackoff
+	}
+
+	return r
+}
+
+// WithDefaults returns the queue config with the defaults for the unset fields
+func (q QueueConfig) WithDefaults() QueueConfig {
+	if q.Size == 0 {
+		q.Size = DefaultQueueSize
+	}
+	if q.Overflow == "" {
+		q.Overflow = OverflowDropOldest
+	}
+	if q.BlockTimeout == 0 {
+		q.BlockTimeout = DefaultBlockTimeout
+	}
+
+	return q
+}
+
+// LoadConfig reads the configuration file and returns the AppConfig object.
+// It returns a *ValidationError if the config has unknown fields or invalid values.
 func LoadConfig(path string) (*AppConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var result AppConfig
-	if err = yaml.Unmarshal(data, &result); err != nil {
-		return nil, err
+	result, problems := ParseConfig(data)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
 	}
 
-	return &result, nil
+	return result, nil
 }
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	RefEnv  = "env"
+	RefFile = "file"
+)
+
+// refPattern matches ${scheme:value} references in reporter config values
+var refPattern = regexp.MustCompile(`\$\{([^}:]*):([^}]*)\}`)
+
+// ErrNoSecretReader is returned by Resolve if the config has secretRefs but no secret reader is given
+var ErrNoSecretReader = errors.New("secretRefs need a kubernetes secret reader")
+
+// SecretReader reads a key of a kubernetes secret
+type SecretReader interface {
+	ReadSecret(namespace, name, key string) (string, error)
+}
+
+// HasReference reports whether the value contains an ${env:} or ${file:} reference
+func HasReference(value string) bool {
+	return refPattern.MatchString(value)
+}
+
+// HasSecretRefs reports whether any reporter reads a config from a kubernetes secret
+func (c *AppConfig) HasSecretRefs() bool {
+	for _, r := range c.Reporters {
+		if len(r.SecretRefs) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve returns a copy of the config with every reference in the reporter configs replaced by its value.
+// The config itself is not changed, so it can be logged without the resolved secrets.
+func Resolve(cfg *AppConfig, secrets SecretReader) (*AppConfig, error) {
+	resolved := *cfg
+	resolved.Reporters = make([]Reporter, len(cfg.Reporters))
+
+	for i, r := range cfg.Reporters {
+		configs := make(map[string]string, len(r.Configs)+len(r.SecretRefs))
+		for key, value := range r.Configs {
+			v, err := resolveValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("reporters[%d].configs.%s: %w", i, key, err)
+			}
+			configs[key] = v
+		}
+
+		keys := make([]string, 0, len(r.SecretRefs))
+		for key := range r.SecretRefs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			ref := r.SecretRefs[key]
+			if secrets == nil {
+				return nil, fmt.Errorf("reporters[%d].secretRefs.%s: %w", i, key, ErrNoSecretReader)
+			}
+			v, err := secrets.ReadSecret(ref.Namespace, ref.Name, ref.Key)
+			if err != nil {
+				return nil, fmt.Errorf("reporters[%d].secretRefs.%s: %w", i, key, err)
+			}
+			configs[key] = v
+		}
+
+		r.Configs, r.SecretRefs = configs, nil
+		resolved.Reporters[i] = r
+	}
+
+	return &resolved, nil
+}
+
+// resolveValue replaces the references in the value
+func resolveValue(value string) (string, error) {
+	var err error
+	resolved := refPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+
+		m := refPattern.FindStringSubmatch(ref)
+		switch m[1] {
+		case RefEnv:
+			v, ok := os.LookupEnv(m[2])
+			if !ok {
+				err = fmt.Errorf("environment variable %s is not set", m[2])
+			}
+			return v
+		case RefFile:
+			data, readErr := os.ReadFile(m[2])
+			if readErr != nil {
+				err = readErr
+			}
+			return strings.TrimRight(string(data), "\r\n")
+		default:
+			err = fmt.Errorf("unknown reference %q", ref)
+			return ""
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resolved, nil
+}
+
+// validateRefs checks the references of a reporter without resolving them
+func validateRefs(p string, r Reporter) []Problem {
+	var problems []Problem
+	for key, value := range r.Configs {
+		for _, m := range refPattern.FindAllStringSubmatch(value, -1) {
+			switch {
+			case m[1] != RefEnv && m[1] != RefFile:
+				problems = append(problems, Problem{Path: p + ".configs." + key, Message: fmt.Sprintf("unknown reference %q, use ${%s:NAME} or ${%s:/path}", m[0], RefEnv, RefFile)})
+			case m[2] == "":
+				problems = append(problems, Problem{Path: p + ".configs." + key, Message: fmt.Sprintf("empty reference %q", m[0])})
+			}
+		}
+	}
+
+	for key, ref := range r.SecretRefs {
+		if _, dup := r.Configs[key]; dup {
+			problems = append(problems, Problem{Path: p + ".secretRefs." + key, Message: "key is also set in configs"})
+		}
+		if ref.Name == "" || ref.Key == "" {
+			problems = append(problems, Problem{Path: p + ".secretRefs." + key, Message: "name and key are required"})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+
+	return problems
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) ReadSecret(namespace, name, key string) (string, error) {
+	v, ok := f[namespace+"/"+name+"/"+key]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("HPA_REPORTER_TEST_URL", "https://hooks.example/abc")
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("xoxb-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &AppConfig{Reporters: []Reporter{{
+		Name: "slack",
+		Type: "slack",
+		Configs: map[string]string{
+			"url":     "${env:HPA_REPORTER_TEST_URL}",
+			"token":   "Bearer ${file:" + file + "}",
+			"timeout": "5s",
+		},
+		SecretRefs: map[string]SecretRef{"channel": {Name: "slack", Key: "channel"}},
+	}}}
+
+	resolved, err := Resolve(cfg, fakeSecrets{"/slack/channel": "#alerts"})
+	if err != nil {
+		t.Fatalf("failed to resolve: %v", err)
+	}
+
+	want := map[string]string{
+		"url":     "https://hooks.example/abc",
+		"token":   "Bearer xoxb-secret",
+		"timeout": "5s",
+		"channel": "#alerts",
+	}
+	for key, v := range want {
+		if got := resolved.Reporters[0].Configs[key]; got != v {
+			t.Errorf("configs.%s = %q, want %q", key, got, v)
+		}
+	}
+
+	if cfg.Reporters[0].Configs["url"] != "${env:HPA_REPORTER_TEST_URL}" {
+		t.Error("Resolve changed the raw config")
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []Reporter{
+		{Configs: map[string]string{"url": "${env:HPA_REPORTER_TEST_UNSET}"}},
+		{Configs: map[string]string{"url": "${file:/nonexistent/hpa-reporter}"}},
+		{SecretRefs: map[string]SecretRef{"url": {Name: "missing", Key: "url"}}},
+	}
+
+	for _, r := range tests {
+		if _, err := Resolve(&AppConfig{Reporters: []Reporter{r}}, fakeSecrets{}); err == nil {
+			t.Errorf("Resolve(%v) expected error", r)
+		}
+	}
+
+	r := Reporter{SecretRefs: map[string]SecretRef{"url": {Name: "slack", Key: "url"}}}
+	if _, err := Resolve(&AppConfig{Reporters: []Reporter{r}}, nil); !errors.Is(err, ErrNoSecretReader) {
+		t.Errorf("expected ErrNoSecretReader, got %v", err)
+	}
+}
+
+func TestValidateRefs(t *testing.T) {
+	problems := validateRefs("reporters[0]", Reporter{
+		Configs: map[string]string{
+			"url":   "${vault:slack}",
+			"token": "${env:}",
+			"ok":    "${env:SLACK_URL}",
+		},
+		SecretRefs: map[string]SecretRef{
+			"url":     {Name: "slack", Key: "url"},
+			"channel": {Name: "slack"},
+		},
+	})
+
+	want := []string{
+		"reporters[0].configs.token",
+		"reporters[0].configs.url",
+		"reporters[0].secretRefs.channel",
+		"reporters[0].secretRefs.url",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	for i, p := range problems {
+		if p.Path != want[i] {
+			t.Errorf("problem %d path %q, want %q", i, p.Path, want[i])
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type thresholdKind int
+
+const (
+	thresholdAbsolute thresholdKind = iota
+	thresholdPercent
+	thresholdMaxMinus
+)
+
+// Threshold is the replica count that raises a warning. It is written as an
+// absolute count ("8"), a percentage of maxReplicas ("80%") or maxReplicas
+// minus N ("max-2"), and is resolved against the live hpa spec.
+type Threshold struct {
+	kind  thresholdKind
+	value int32
+	raw   string
+}
+
+// ParseThreshold parses the threshold notation
+func ParseThreshold(s string) (Threshold, error) {
+	raw := strings.TrimSpace(s)
+	v := strings.ReplaceAll(raw, " ", "")
+
+	switch {
+	case strings.HasSuffix(v, "%"):
+		n, err := strconv.ParseInt(strings.TrimSuffix(v, "%"), 10, 32)
+		if err != nil || n <= 0 || n > 100 {
+			return Threshold{}, fmt.Errorf("invalid threshold %q: percentage must be between 1%% and 100%%", s)
+		}
+		return Threshold{kind: thresholdPercent, value: int32(n), raw: raw}, nil
+
+	case strings.HasPrefix(v, "max-"):
+		n, err := strconv.ParseInt(strings.TrimPrefix(v, "max-"), 10, 32)
+		if err != nil || n < 0 {
+			return Threshold{}, fmt.Errorf("invalid threshold %q: expected max-N with N >= 0", s)
+		}
+		return Threshold{kind: thresholdMaxMinus, value: int32(n), raw: raw}, nil
+
+	default:
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return Threshold{}, fmt.Errorf("invalid threshold %q: expected a replica count, a percentage (80%%) or max-N", s)
+		}
+		return Threshold{kind: thresholdAbsolute, value: int32(n), raw: raw}, nil
+	}
+}
+
+// MustParseThreshold is like ParseThreshold but panics if the threshold cannot be parsed
+func MustParseThreshold(s string) Threshold {
+	t, err := ParseThreshold(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+// Resolve returns the replica count of the threshold for maxReplicas.
+// relative thresholds never resolve below 1.
+func (t Threshold) Resolve(maxReplicas int32) int32 {
+	var result int32
+	switch t.kind {
+	case thresholdPercent:
+		result = int32(math.Ceil(float64(maxReplicas) * float64(t.value) / 100))
+	case thresholdMaxMinus:
+		result = maxReplicas - t.value
+	default:
+		return t.value
+	}
+
+	if result < 1 {
+		return 1
+	}
+
+	return result
+}
+
+// IsZero reports whether the threshold is unset or an absolute 0
+func (t Threshold) IsZero() bool {
+	return t.kind == thresholdAbsolute && t.value == 0
+}
+
+// String returns the threshold as written in the config
+func (t Threshold) String() string {
+	if t.raw == "" {
+		return strconv.Itoa(int(t.value))
+	}
+
+	return t.raw
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (t *Threshold) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	parsed, err := ParseThreshold(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (t Threshold) MarshalYAML() (interface{}, error) {
+	return t.String(), nil
+}
+
+// MarshalJSON implements json.Marshaler so that the threshold is logged as written
+func (t Threshold) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThresholdResolve(t *testing.T) {
+	tests := []struct {
+		threshold string
+		max       int32
+		want      int32
+	}{
+		{"8", 10, 8},
+		{"8", 20, 8},
+		{"80%", 10, 8},
+		{"80%", 20, 16},
+		{"80%", 7, 6},
+		{"100%", 10, 10},
+		{"max-2", 10, 8},
+		{"max - 2", 20, 18},
+		{"max-0", 10, 10},
+		{"max-5", 3, 1},
+	}
+
+	for _, tt := range tests {
+		th, err := ParseThreshold(tt.threshold)
+		if err != nil {
+			t.Errorf("ParseThreshold(%q) error: %v", tt.threshold, err)
+			continue
+		}
+		if got := th.Resolve(tt.max); got != tt.want {
+			t.Errorf("ParseThreshold(%q).Resolve(%d) = %d, want %d", tt.threshold, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestParseThresholdInvalid(t *testing.T) {
+	for _, s := range []string{"", "abc", "-1", "0%", "101%", "80.5%", "max+2", "max-", "max-x", "min-2"} {
+		if _, err := ParseThreshold(s); err == nil {
+			t.Errorf("ParseThreshold(%q) expected error", s)
+		}
+	}
+}
+
+func TestLoadConfigThreshold(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+
+	if err := os.WriteFile(path, []byte("hpa:\n  - name: a\n    namespace: test\n    threshold: 80%\n  - name: b\n    namespace: test\n    threshold: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if got := cfg.Hpa[0].Threshold.Resolve(10); got != 8 {
+		t.Errorf("expected 80%% of 10 to be 8, got %d", got)
+	}
+	if got := cfg.Hpa[1].Threshold.Resolve(10); got != 3 {
+		t.Errorf("expected 3, got %d", got)
+	}
+
+	if err = os.WriteFile(path, []byte("hpa:\n  - name: a\n    namespace: test\n    threshold: eighty\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadConfig(path); err == nil {
+		t.Error("expected error for invalid threshold")
+	}
+}
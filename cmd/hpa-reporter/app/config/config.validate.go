@@ -0,0 +1,345 @@
+package config
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Problem is a config error at a yaml path such as "hpa[0].threshold"
+type Problem struct {
+	Path    string
+	Message string
+}
+
+// String returns the problem as "path: message"
+func (p Problem) String() string {
+	if p.Path == "" {
+		return p.Message
+	}
+
+	return p.Path + ": " + p.Message
+}
+
+// ValidationError holds every problem found in the config
+type ValidationError struct {
+	Problems []Problem
+}
+
+// Error implements error
+func (e *ValidationError) Error() string {
+	lines := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		lines = append(lines, p.String())
+	}
+
+	return fmt.Sprintf("%d config problem(s): %s", len(e.Problems), strings.Join(lines, "; "))
+}
+
+// ParseConfig decodes the config strictly and validates it.
+// the returned config is nil only if the yaml itself cannot be read.
+func ParseConfig(data []byte) (*AppConfig, []Problem) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, []Problem{{Message: err.Error()}}
+	}
+
+	// the invalid values are removed from raw, so the rest of the config can still be decoded and checked
+	problems := decodeProblems(raw, reflect.TypeOf(AppConfig{}), "")
+	if len(problems) > 0 {
+		cleaned, err := yaml.Marshal(raw)
+		if err != nil {
+			return nil, append(problems, Problem{Message: err.Error()})
+		}
+		data = cleaned
+	}
+
+	var result AppConfig
+	if err := yaml.Unmarshal(data, &result); err != nil {
+		if typeErr, ok := err.(*yaml.TypeError); ok {
+			for _, e := range typeErr.Errors {
+				problems = append(problems, Problem{Message: e})
+			}
+		} else {
+			problems = append(problems, Problem{Message: err.Error()})
+		}
+	}
+
+	// a value that could not be decoded is reported once, not again as missing
+	reported := make(map[string]struct{}, len(problems))
+	for _, p := range problems {
+		reported[p.Path] = struct{}{}
+	}
+	for _, p := range Validate(&result) {
+		if _, ok := reported[p.Path]; !ok {
+			problems = append(problems, p)
+		}
+	}
+
+	return &result, problems
+}
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// decodeProblems walks the generic yaml node along the config type and reports
+// unknown fields and values rejected by custom unmarshalers with their path.
+// the reported keys are deleted from the node.
+func decodeProblems(node interface{}, t reflect.Type, p string) []Problem {
+	if node == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		out, err := yaml.Marshal(node)
+		if err == nil {
+			err = yaml.Unmarshal(out, reflect.New(t).Interface())
+		}
+		if err != nil {
+			return []Problem{{Path: p, Message: strings.TrimPrefix(err.Error(), "yaml: ")}}
+		}
+		return nil
+	}
+
+	var problems []Problem
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return []Problem{{Path: p, Message: fmt.Sprintf("expected a mapping, got %T", node)}}
+		}
+
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if name != "" && name != "-" {
+				fields[name] = t.Field(i).Type
+			}
+		}
+
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, fmt.Sprint(k))
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			fieldType, ok := fields[k]
+			if !ok {
+				problems = append(problems, Problem{Path: join(p, k), Message: "unknown field"})
+				deleteKey(m, k)
+				continue
+			}
+			if fieldProblems := decodeProblems(lookupKey(m, k), fieldType, join(p, k)); len(fieldProblems) > 0 {
+				problems = append(problems, fieldProblems...)
+				if isLeaf(fieldType) {
+					deleteKey(m, k)
+				}
+			}
+		}
+	case reflect.Slice:
+		items, ok := node.([]interface{})
+		if !ok {
+			return []Problem{{Path: p, Message: fmt.Sprintf("expected a list, got %T", node)}}
+		}
+		for i, item := range items {
+			problems = append(problems, decodeProblems(item, t.Elem(), fmt.Sprintf("%s[%d]", p, i))...)
+		}
+	case reflect.Map:
+		m, ok := node.(map[interface{}]interface{})
+		if !ok {
+			return []Problem{{Path: p, Message: fmt.Sprintf("expected a mapping, got %T", node)}}
+		}
+		for k, v := range m {
+			if valueProblems := decodeProblems(v, t.Elem(), join(p, fmt.Sprint(k))); len(valueProblems) > 0 {
+				problems = append(problems, valueProblems...)
+				if isLeaf(t.Elem()) {
+					delete(m, k)
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// validateRoute checks the matchers and the receivers of the route and its children
+func validateRoute(p string, r *Route, reporters map[string]int) []Problem {
+	var problems []Problem
+
+	for i, receiver := range r.Receivers {
+		if _, ok := reporters[receiver]; !ok {
+			problems = append(problems, Problem{Path: fmt.Sprintf("%s.receivers[%d]", p, i), Message: fmt.Sprintf("unknown reporter %q", receiver)})
+		}
+	}
+
+	m := r.Match
+	if _, err := path.Match(m.Namespace, ""); err != nil {
+		problems = append(problems, Problem{Path: p + ".match.namespace", Message: fmt.Sprintf("invalid glob %q: %v", m.Namespace, err)})
+	}
+	if m.NameRegex != "" {
+		if _, err := regexp.Compile(m.NameRegex); err != nil {
+			problems = append(problems, Problem{Path: p + ".match.nameRegex", Message: err.Error()})
+		}
+	}
+	if strings.TrimSpace(m.Selector) != "" {
+		if _, err := labels.Parse(m.Selector); err != nil {
+			problems = append(problems, Problem{Path: p + ".match.selector", Message: err.Error()})
+		}
+	}
+	for i, level := range m.Levels {
+		switch level {
+		case message.LevelWarning, message.LevelCritical, message.LevelResolved:
+		default:
+			problems = append(problems, Problem{Path: fmt.Sprintf("%s.match.levels[%d]", p, i), Message: fmt.Sprintf("unknown level %q, use %s, %s or %s", level, message.LevelWarning, message.LevelCritical, message.LevelResolved)})
+		}
+	}
+
+	for i := range r.Routes {
+		problems = append(problems, validateRoute(fmt.Sprintf("%s.routes[%d]", p, i), &r.Routes[i], reporters)...)
+	}
+
+	return problems
+}
+
+// lookupKey returns the value of the key of a generic yaml mapping
+func lookupKey(m map[interface{}]interface{}, key string) interface{} {
+	for k, v := range m {
+		if fmt.Sprint(k) == key {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// deleteKey removes the key from a generic yaml mapping
+func deleteKey(m map[interface{}]interface{}, key string) {
+	for k := range m {
+		if fmt.Sprint(k) == key {
+			delete(m, k)
+		}
+	}
+}
+
+// isLeaf reports whether the problems of a value of the type are its own and not of nested fields
+func isLeaf(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Map:
+		return false
+	}
+
+	return true
+}
+
+// join appends the key to the yaml path
+func join(p, key string) string {
+	if p == "" {
+		return key
+	}
+
+	return p + "." + key
+}
+
+// Validate checks the semantic of the config and returns every problem
+func Validate(cfg *AppConfig) []Problem {
+	var problems []Problem
+
+	names := make(map[string]int)
+	for i, r := range cfg.Reporters {
+		p := fmt.Sprintf("reporters[%d]", i)
+		if r.Name == "" {
+			problems = append(problems, Problem{Path: p + ".name", Message: "name is required"})
+		} else if first, dup := names[r.Name]; dup {
+			problems = append(problems, Problem{Path: p + ".name", Message: fmt.Sprintf("duplicate reporter name %q, first defined at reporters[%d]", r.Name, first)})
+		} else {
+			names[r.Name] = i
+		}
+		if r.Type == "" {
+			problems = append(problems, Problem{Path: p + ".type", Message: "type is required"})
+		}
+		problems = append(problems, validateRefs(p, r)...)
+
+		if r.Queue.Size < 0 {
+			problems = append(problems, Problem{Path: p + ".queue.size", Message: "must not be negative"})
+		}
+		switch r.Queue.Overflow {
+		case "", OverflowDropOldest, OverflowDropNewest, OverflowBlock:
+		default:
+			problems = append(problems, Problem{Path: p + ".queue.overflow", Message: fmt.Sprintf("unknown overflow policy %q, use %s, %s or %s", r.Queue.Overflow, OverflowDropOldest, OverflowDropNewest, OverflowBlock)})
+		}
+		if r.Queue.BlockTimeout < 0 {
+			problems = append(problems, Problem{Path: p + ".queue.blockTimeout", Message: "must not be negative"})
+		}
+		if r.Retry.MaxAttempts < 0 {
+			problems = append(problems, Problem{Path: p + ".retry.maxAttempts", Message: "must not be negative"})
+		}
+		if r.Retry.InitialBackoff < 0 {
+			problems = append(problems, Problem{Path: p + ".retry.initialBackoff", Message: "must not be negative"})
+		}
+		if r.Retry.MaxBackoff < 0 {
+			problems = append(problems, Problem{Path: p + ".retry.maxBackoff", Message: "must not be negative"})
+		}
+		if retry := r.Retry.WithDefaults(); retry.MaxBackoff < retry.InitialBackoff {
+			problems = append(problems, Problem{Path: p + ".retry.maxBackoff", Message: "must not be less than initialBackoff"})
+		}
+	}
+
+	if cfg.Route != nil {
+		if len(cfg.Route.Receivers) == 0 {
+			problems = append(problems, Problem{Path: "route.receivers", Message: "the root route needs receivers for the messages no route matches"})
+		}
+		problems = append(problems, validateRoute("route", cfg.Route, names)...)
+	}
+
+	for i, h := range cfg.Hpa {
+		p := fmt.Sprintf("hpa[%d]", i)
+		if h.Name == "" && h.NameRegex == "" && h.Namespace == "" && strings.TrimSpace(h.Selector) == "" {
+			problems = append(problems, Problem{Path: p, Message: "one of name, nameRegex, namespace or selector is required, use hpaDefault to select every hpa"})
+		}
+		if h.Threshold.IsZero() {
+			problems = append(problems, Problem{Path: p + ".threshold", Message: "threshold is required and must be greater than 0"})
+		}
+		if _, err := path.Match(h.Namespace, ""); err != nil {
+			problems = append(problems, Problem{Path: p + ".namespace", Message: fmt.Sprintf("invalid glob %q: %v", h.Namespace, err)})
+		}
+		if h.NameRegex != "" {
+			if _, err := regexp.Compile(h.NameRegex); err != nil {
+				problems = append(problems, Problem{Path: p + ".nameRegex", Message: err.Error()})
+			}
+		}
+		if strings.TrimSpace(h.Selector) != "" {
+			if _, err := labels.Parse(h.Selector); err != nil {
+				problems = append(problems, Problem{Path: p + ".selector", Message: err.Error()})
+			}
+		}
+	}
+
+	if cfg.HpaDefault.Enabled && cfg.HpaDefault.Threshold.IsZero() {
+		problems = append(problems, Problem{Path: "hpaDefault.threshold", Message: "threshold is required and must be greater than 0"})
+	}
+
+	if cfg.Alert.RenotifyInterval < 0 {
+		problems = append(problems, Problem{Path: "alert.renotifyInterval", Message: "must not be negative"})
+	}
+
+	if cfg.Spool.RetryInterval < 0 {
+		problems = append(problems, Problem{Path: "spool.retryInterval", Message: "must not be negative"})
+	}
+	if cfg.Spool.MaxAge < 0 {
+		problems = append(problems, Problem{Path: "spool.maxAge", Message: "must not be negative"})
+	}
+
+	return problems
+}
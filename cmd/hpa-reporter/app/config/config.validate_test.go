@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseConfigProblems(t *testing.T) {
+	data := []byte(`
+reporters:
+  - name: a
+    type: stdout
+  - name: a
+    type: stdout
+  - type: stdout
+hpa:
+  - name: web
+    threshhold: 3
+  - name: api
+    threshold: -1
+  - nameRegex: "("
+    threshold: 0
+  - threshold: 3
+hpaDefault:
+  enabled: true
+alert:
+  renotifyInterval: -1s
+`)
+
+	_, problems := ParseConfig(data)
+
+	want := map[string]bool{
+		"reporters[1].name":      false,
+		"reporters[2].name":      false,
+		"hpa[0].threshhold":      false,
+		"hpa[0].threshold":       false,
+		"hpa[1].threshold":       false,
+		"hpa[2].nameRegex":       false,
+		"hpa[2].threshold":       false,
+		"hpa[3]":                 false,
+		"hpaDefault.threshold":   false,
+		"alert.renotifyInterval": false,
+	}
+	for _, p := range problems {
+		if _, ok := want[p.Path]; !ok {
+			t.Errorf("unexpected problem %s", p)
+			continue
+		}
+		if want[p.Path] {
+			t.Errorf("problem reported twice %s", p)
+		}
+		want[p.Path] = true
+	}
+	for path, found := range want {
+		if !found {
+			t.Errorf("expected a problem at %s", path)
+		}
+	}
+}
+
+func TestParseConfigSyntaxError(t *testing.T) {
+	cfg, problems := ParseConfig([]byte("hpa: [\n"))
+	if cfg != nil || len(problems) != 1 {
+		t.Errorf("expected a single syntax problem, got %v", problems)
+	}
+}
+
+func TestLoadConfigValidationError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(path, []byte("hpa:\n  - name: a\n    threshold: 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(path)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(validationErr.Problems) != 1 || validationErr.Problems[0].Path != "hpa[0].threshold" {
+		t.Errorf("unexpected problems: %v", validationErr.Problems)
+	}
+}
+
+func TestValidateRoute(t *testing.T) {
+	_, problems := ParseConfig([]byte(`
+reporters:
+  - name: slack
+    type: stdout
+route:
+  routes:
+    - match:
+        nameRegex: "("
+        levels: [error]
+      receivers: [slack, pager]
+`))
+
+	want := []string{
+		"route.receivers",
+		"route.routes[0].receivers[1]",
+		"route.routes[0].match.nameRegex",
+		"route.routes[0].match.levels[0]",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	for i, p := range problems {
+		if p.Path != want[i] {
+			t.Errorf("problem %d path %q, want %q", i, p.Path, want[i])
+		}
+	}
+}
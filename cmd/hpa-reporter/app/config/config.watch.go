@@ -0,0 +1,78 @@
+package config
+
+import (
+	"crypto/sha256"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"os"
+	"time"
+)
+
+const (
+	DefaultWatchInterval = 10 * time.Second
+)
+
+// Watcher polls the config file and notifies C when its content changes.
+// kubernetes updates a mounted ConfigMap by swapping a symlink, so the content
+// is compared instead of the modification time.
+type Watcher struct {
+	C chan struct{}
+
+	path     string
+	interval time.Duration
+	sum      [sha256.Size]byte
+	shutdown chan struct{}
+}
+
+// NewWatcher creates a new watcher of the config file
+func NewWatcher(path string, interval time.Duration) (*Watcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{
+		C:        make(chan struct{}, 1),
+		path:     path,
+		interval: interval,
+		sum:      sha256.Sum256(data),
+		shutdown: make(chan struct{}),
+	}, nil
+}
+
+// Run polls the config file until Stop is called
+func (w *Watcher) Run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			data, err := os.ReadFile(w.path)
+			if err != nil {
+				// the file briefly disappears while the symlink is swapped
+				logger.Debug("[config] failed to read config file", zap.String("path", w.path), zap.Error(err))
+				continue
+			}
+
+			sum := sha256.Sum256(data)
+			if sum == w.sum {
+				continue
+			}
+			w.sum = sum
+
+			select {
+			case w.C <- struct{}{}:
+			default:
+			}
+		case <-w.shutdown:
+			break LOOP
+		}
+	}
+}
+
+// Stop stops the watcher
+func (w *Watcher) Stop() {
+	close(w.shutdown)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+
+	// simulate the ConfigMap layout: config.yml -> ..data/config.yml, ..data -> ..v1
+	for _, v := range []string{"..v1", "..v2"} {
+		if err := os.Mkdir(filepath.Join(dir, v), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, v, "config.yml"), []byte("version: "+v), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("..v1", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.yml")
+	if err := os.Symlink(filepath.Join("..data", "config.yml"), path); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWatcher(path, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	go w.Run()
+	defer w.Stop()
+
+	select {
+	case <-w.C:
+		t.Fatal("expected no change before the symlink swap")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// atomic swap of the ..data symlink
+	if err = os.Symlink("..v2", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-w.C:
+	case <-time.After(time.Second):
+		t.Fatal("expected change after the symlink swap")
+	}
+}
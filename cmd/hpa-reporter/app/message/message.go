@@ -1,16 +1,65 @@
 package message
 
+import (
+	"time"
+)
+
 const (
 	LevelWarning  = "warning"
 	LevelCritical = "critical"
+	LevelResolved = "resolved"
 )
 
+const (
+	AlertReplicas           = "Replicas"
+	AlertScalingLimited     = "ScalingLimited"
+	AlertMetricsUnavailable = "MetricsUnavailable"
+	AlertUnableToScale      = "UnableToScale"
+)
+
+var summaries = map[string]string{
+	AlertReplicas:           "replicas reached threshold",
+	AlertScalingLimited:     "wants more replicas than max",
+	AlertMetricsUnavailable: "cannot fetch metrics",
+	AlertUnableToScale:      "unable to scale",
+}
+
 // Data is message data
 type Data struct {
 	Time            string
 	Level           string
 	Name            string
 	Namespace       string
+	Labels          map[string]string
+	Annotations     map[string]string
 	CurrentReplicas int32
+	DesiredReplicas int32
 	MaxReplicas     int32
+
+	// TargetKind and TargetName are the workload scaled by the hpa, such as Deployment/my-app
+	TargetKind string
+	TargetName string
+
+	// Alert is the kind of the alert, ConditionReason and ConditionMessage are
+	// copied from the hpa status condition that raised it.
+	Alert            string
+	ConditionReason  string
+	ConditionMessage string
+
+	// Receivers limits the reporters that get the message to these names, within the receivers of the route if one is set
+	Receivers []string
+
+	// Duration is how long the hpa was at warning or critical and FiringLevel is the level it had.
+	// they are only set on resolved messages.
+	Duration    time.Duration
+	FiringLevel string
+}
+
+// Summary returns a short human readable description of the alert
+func (d *Data) Summary() string {
+	if summary, ok := summaries[d.Alert]; ok {
+		return summary
+	}
+
+	return summaries[AlertReplicas]
 }
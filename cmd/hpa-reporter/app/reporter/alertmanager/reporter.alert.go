@@ -0,0 +1,141 @@
+package alertmanager
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"sync"
+	"time"
+)
+
+const (
+	AlertName = "HPAMaxReplicas"
+
+	LabelAlertName = "alertname"
+	LabelNamespace = "namespace"
+	LabelHPA       = "hpa"
+	LabelSeverity  = "severity"
+	LabelAlert     = "alert"
+	LabelCluster   = "cluster"
+)
+
+// alert is a postable alert of the alertmanager v2 api
+type alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// newAlert builds the labels and annotations of the message, the times are set by the caller
+func (r *Reporter) newAlert(msg *message.Data) alert {
+	severity := msg.Level
+	if msg.Level == message.LevelResolved {
+		severity = msg.FiringLevel
+	}
+
+	labels := make(map[string]string, len(r.labels)+6)
+	for k, v := range r.labels {
+		labels[k] = v
+	}
+	labels[LabelAlertName] = AlertName
+	labels[LabelNamespace] = msg.Namespace
+	labels[LabelHPA] = msg.Name
+	labels[LabelSeverity] = severity
+	if msg.Alert != "" {
+		labels[LabelAlert] = msg.Alert
+	}
+	if r.cluster != "" {
+		labels[LabelCluster] = r.cluster
+	}
+
+	annotations := map[string]string{
+		"summary":          fmt.Sprintf("HPA %s/%s %s", msg.Namespace, msg.Name, msg.Summary()),
+		"current_replicas": fmt.Sprint(msg.CurrentReplicas),
+		"desired_replicas": fmt.Sprint(msg.DesiredReplicas),
+		"max_replicas":     fmt.Sprint(msg.MaxReplicas),
+	}
+	if msg.ConditionReason != "" {
+		annotations["description"] = fmt.Sprintf("%s: %s", msg.ConditionReason, msg.ConditionMessage)
+	}
+	if msg.TargetName != "" {
+		annotations["workload"] = msg.TargetKind + "/" + msg.TargetName
+	}
+
+	return alert{Labels: labels, Annotations: annotations}
+}
+
+// sameLabels returns true if alertmanager sees both label sets as the same alert
+func sameLabels(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// store keeps the firing alert of every alert kind of the hpas so it can be re-sent, it is keyed by namespace/name/alert.
+// it is kept across config reloads and shared with the resend loop, so it has its own lock.
+type store struct {
+	mu     sync.Mutex
+	alerts map[string]alert
+}
+
+// newStore creates an empty store
+func newStore() *store {
+	return &store{alerts: make(map[string]alert)}
+}
+
+// get returns a copy of the firing alert of the key, nil if it is not firing
+func (s *store) get(key string) *alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.alerts[key]
+	if !ok {
+		return nil
+	}
+
+	return &a
+}
+
+// set records the firing alert of the key
+func (s *store) set(key string, a alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.alerts[key] = a
+}
+
+// remove forgets the alert of the key and returns it, nil if it was not firing
+func (s *store) remove(key string) *alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.alerts[key]
+	if !ok {
+		return nil
+	}
+	delete(s.alerts, key)
+
+	return &a
+}
+
+// refresh moves the end of every firing alert to endsAt and returns them
+func (s *store) refresh(endsAt time.Time) []alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]alert, 0, len(s.alerts))
+	for key, a := range s.alerts {
+		a.EndsAt = endsAt
+		s.alerts[key] = a
+		result = append(result, a)
+	}
+
+	return result
+}
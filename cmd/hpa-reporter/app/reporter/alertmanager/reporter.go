@@ -0,0 +1,231 @@
+package alertmanager
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	reporter.Register(Type, func(cfg config.Reporter, shutdown chan struct{}) (reporter.Reporter, error) {
+		r, err := CreateReporter(cfg, shutdown)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+	reporter.RegisterValidator(Type, Validate)
+}
+
+const (
+	Type = "alertmanager"
+
+	// ConfigURLs is a comma separated list of alertmanager urls, every alertmanager of a HA cluster gets the alerts
+	ConfigURLs           = "urls"
+	ConfigCluster        = "cluster"
+	ConfigLabels         = "labels"
+	ConfigResendInterval = "resendInterval"
+	ConfigTimeout        = "timeout"
+
+	DefaultResendInterval = time.Minute
+	DefaultTimeout        = 5 * time.Second
+
+	alertsPath = "/api/v2/alerts"
+)
+
+// Reporter posts alerts to the alertmanager v2 api and re-sends the firing alerts
+// every resendInterval so alertmanager does not resolve them.
+type Reporter struct {
+	shutdown chan struct{}
+
+	name string
+
+	urls           []string
+	cluster        string
+	labels         map[string]string
+	resendInterval time.Duration
+	alerts         *store
+	client         *http.Client
+	now            func() time.Time
+}
+
+// Report posts the alert of the message. every alert kind of the hpa is its own alert, when the severity of
+// a kind changes the labels change, so the alert of the previous severity is ended next to the new one.
+func (r *Reporter) Report(msg *message.Data) error {
+	now := r.now()
+	key := msg.Namespace + "/" + msg.Name + "/" + msg.Alert
+
+	if msg.Level == message.LevelResolved {
+		a := r.newAlert(msg)
+		a.StartsAt = now.Add(-msg.Duration)
+		if prev := r.alerts.remove(key); prev != nil {
+			a.Labels, a.StartsAt = prev.Labels, prev.StartsAt
+		}
+		a.EndsAt = now
+		return r.post([]alert{a})
+	}
+
+	a := r.newAlert(msg)
+	a.StartsAt = now
+	a.EndsAt = r.endsAt(now)
+
+	var batch []alert
+	if prev := r.alerts.get(key); prev != nil {
+		if sameLabels(prev.Labels, a.Labels) {
+			a.StartsAt = prev.StartsAt
+		} else {
+			prev.EndsAt = now
+			batch = append(batch, *prev)
+		}
+	}
+	r.alerts.set(key, a)
+
+	return r.post(append(batch, a))
+}
+
+// endsAt returns the end of a firing alert, alertmanager resolves it if it is not re-sent before
+func (r *Reporter) endsAt(now time.Time) time.Time {
+	return now.Add(4 * r.resendInterval)
+}
+
+// resend posts the firing alerts again every resendInterval until shutdown
+func (r *Reporter) resend() {
+	ticker := time.NewTicker(r.resendInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.shutdown:
+			return
+		case <-ticker.C:
+			alerts := r.alerts.refresh(r.endsAt(r.now()))
+			if len(alerts) == 0 {
+				continue
+			}
+			if err := r.post(alerts); err != nil {
+				logger.Warn("failed to re-send firing alerts", zap.String("reporter", r.name), zap.Int("alerts", len(alerts)), zap.Error(err))
+			}
+		}
+	}
+}
+
+// post sends the alerts to every alertmanager, the alertmanagers of a HA cluster do not share the alerts they receive.
+// it fails only if no alertmanager accepted them.
+func (r *Reporter) post(alerts []alert) error {
+	var accepted int
+	var lastErr error
+	for _, u := range r.urls {
+		if err := reporter.PostJSON(r.client, u+alertsPath, nil, alerts, nil); err != nil {
+			logger.Warn("alertmanager did not accept alerts", zap.String("reporter", r.name), zap.String("url", u), zap.Error(err))
+			lastErr = err
+			continue
+		}
+		accepted++
+	}
+
+	if accepted > 0 {
+		return nil
+	}
+	if len(r.urls) > 1 {
+		return fmt.Errorf("all %d alertmanagers failed, last: %w", len(r.urls), lastErr)
+	}
+	return lastErr
+}
+
+// Validate checks the alertmanager reporter config
+func Validate(cfg config.Reporter) []config.Problem {
+	var problems []config.Problem
+	problem := func(key, format string, args ...interface{}) {
+		problems = append(problems, config.Problem{Path: "configs." + key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	urls := splitList(cfg.Configs[ConfigURLs])
+	if len(urls) == 0 {
+		problem(ConfigURLs, "%s is required", ConfigURLs)
+	}
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); (err != nil || parsed.Scheme == "" || parsed.Host == "") && !config.HasReference(u) {
+			problem(ConfigURLs, "invalid url %q", u)
+		}
+	}
+
+	if _, err := parseLabels(cfg.Configs[ConfigLabels]); err != nil {
+		problem(ConfigLabels, "%v", err)
+	}
+
+	for _, key := range []string{ConfigResendInterval, ConfigTimeout} {
+		if v, ok := cfg.Configs[key]; ok {
+			if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+				problem(key, "invalid duration %q", v)
+			}
+		}
+	}
+
+	return problems
+}
+
+// CreateReporter creates a new alertmanager reporter and starts re-sending the firing alerts
+func CreateReporter(cfg config.Reporter, shutdown chan struct{}) (*Reporter, error) {
+	if problems := Validate(cfg); len(problems) > 0 {
+		return nil, fmt.Errorf("alertmanager reporter(%s): %w", cfg.Name, &config.ValidationError{Problems: problems})
+	}
+
+	timeout := DefaultTimeout
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		timeout, _ = time.ParseDuration(v)
+	}
+
+	r := &Reporter{
+		shutdown:       shutdown,
+		name:           cfg.Name,
+		cluster:        cfg.Configs[ConfigCluster],
+		resendInterval: DefaultResendInterval,
+		alerts:         reporter.State(cfg, newStore),
+		client:         &http.Client{Timeout: timeout},
+		now:            time.Now,
+	}
+	for _, u := range splitList(cfg.Configs[ConfigURLs]) {
+		r.urls = append(r.urls, strings.TrimSuffix(u, "/"))
+	}
+	r.labels, _ = parseLabels(cfg.Configs[ConfigLabels])
+	if v, ok := cfg.Configs[ConfigResendInterval]; ok {
+		r.resendInterval, _ = time.ParseDuration(v)
+	}
+
+	go r.resend()
+
+	return r, nil
+}
+
+// splitList splits a comma separated list and drops the empty items
+func splitList(s string) []string {
+	var result []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// parseLabels parses a comma separated list of key=value labels
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, item := range splitList(s) {
+		k, v, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid label %q, use key=value", item)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,215 @@
+package alertmanager
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/reportertest"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// posts returns the alerts of every post to the server
+func posts(t *testing.T, srv *reportertest.Server) [][]alert {
+	var result [][]alert
+	for _, req := range srv.Requests() {
+		if req.Path != alertsPath {
+			t.Errorf("unexpected path %s", req.Path)
+		}
+		var alerts []alert
+		req.Decode(t, &alerts)
+		result = append(result, alerts)
+	}
+
+	return result
+}
+
+func TestReportLifecycle(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusOK, "")
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	r, err := CreateReporter(config.Reporter{Name: "lifecycle", Configs: map[string]string{
+		ConfigURLs:    srv.URL,
+		ConfigCluster: "prod-1",
+		ConfigLabels:  "team=platform",
+	}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	msg := &message.Data{Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test", CurrentReplicas: 8, MaxReplicas: 10}
+	msg.Level = message.LevelWarning
+	if err = r.Report(msg); err != nil {
+		t.Fatalf("failed to send warning: %v", err)
+	}
+	now = now.Add(time.Minute)
+	critical := *msg
+	critical.Level = message.LevelCritical
+	if err = r.Report(&critical); err != nil {
+		t.Fatalf("failed to send critical: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err = r.Report(&message.Data{Level: message.LevelResolved, FiringLevel: message.LevelCritical, Alert: message.AlertReplicas,
+		Name: "my-hpa", Namespace: "test", Duration: 2 * time.Minute}); err != nil {
+		t.Fatalf("failed to send resolved: %v", err)
+	}
+
+	got := posts(t, srv)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 posts, got %d", len(got))
+	}
+
+	warning := got[0][0]
+	if warning.Labels[LabelAlertName] != AlertName || warning.Labels[LabelSeverity] != message.LevelWarning ||
+		warning.Labels[LabelHPA] != "my-hpa" || warning.Labels[LabelCluster] != "prod-1" || warning.Labels["team"] != "platform" {
+		t.Errorf("unexpected labels: %v", warning.Labels)
+	}
+	if warning.Annotations["max_replicas"] != "10" || !strings.Contains(warning.Annotations["summary"], "test/my-hpa") {
+		t.Errorf("unexpected annotations: %v", warning.Annotations)
+	}
+	if !warning.EndsAt.Equal(warning.StartsAt.Add(4 * DefaultResendInterval)) {
+		t.Errorf("expected firing alert to end after 4 resend intervals, got %s - %s", warning.StartsAt, warning.EndsAt)
+	}
+
+	// the severity label changed, the warning alert is ended next to the critical one
+	if len(got[1]) != 2 || got[1][0].Labels[LabelSeverity] != message.LevelWarning || !got[1][0].EndsAt.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("expected the warning alert to be ended, got %+v", got[1])
+	}
+	if got[1][1].Labels[LabelSeverity] != message.LevelCritical {
+		t.Errorf("expected critical alert, got %v", got[1][1].Labels)
+	}
+
+	resolved := got[2][0]
+	if !sameLabels(resolved.Labels, got[1][1].Labels) || !resolved.StartsAt.Equal(got[1][1].StartsAt) || !resolved.EndsAt.Equal(now) {
+		t.Errorf("expected the critical alert to be resolved, got %+v", resolved)
+	}
+}
+
+func TestReportAlertKinds(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusOK, "")
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	r, err := CreateReporter(config.Reporter{Name: "kinds", Configs: map[string]string{ConfigURLs: srv.URL}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	for _, kind := range []string{message.AlertReplicas, message.AlertScalingLimited} {
+		if err = r.Report(&message.Data{Level: message.LevelCritical, Alert: kind, Name: "my-hpa", Namespace: "test"}); err != nil {
+			t.Fatalf("failed to send %s: %v", kind, err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	// the second kind is its own alert, the first one keeps firing
+	got := posts(t, srv)
+	if len(got) != 2 || len(got[1]) != 1 || got[1][0].Labels[LabelAlert] != message.AlertScalingLimited {
+		t.Fatalf("expected only the second alert kind to be posted, got %+v", got)
+	}
+	firing := r.alerts.refresh(now)
+	if len(firing) != 2 {
+		t.Errorf("expected both alert kinds to be re-sent, got %+v", firing)
+	}
+
+	if err = r.Report(&message.Data{Level: message.LevelResolved, FiringLevel: message.LevelCritical, Alert: message.AlertScalingLimited,
+		Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send resolved: %v", err)
+	}
+	if firing = r.alerts.refresh(now); len(firing) != 1 || firing[0].Labels[LabelAlert] != message.AlertReplicas {
+		t.Errorf("expected the Replicas alert to keep firing, got %+v", firing)
+	}
+}
+
+func TestReportHA(t *testing.T) {
+	down := reportertest.NewServer(t, http.StatusServiceUnavailable, "unavailable")
+	first := reportertest.NewServer(t, http.StatusOK, "")
+	second := reportertest.NewServer(t, http.StatusOK, "")
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	r, err := CreateReporter(config.Reporter{Name: "ha", Configs: map[string]string{
+		ConfigURLs: strings.Join([]string{first.URL, down.URL, second.URL + "/"}, ", "),
+	}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	if err = r.Report(&message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("expected the alert to be accepted, got %v", err)
+	}
+	// alertmanagers do not share the alerts they receive, every one of them gets the alert
+	if len(posts(t, first)) != 1 || len(posts(t, second)) != 1 || len(down.Requests()) != 1 {
+		t.Errorf("expected every alertmanager to get the alert, got %d, %d and %d posts",
+			len(first.Requests()), len(down.Requests()), len(second.Requests()))
+	}
+
+	first.SetStatus(http.StatusServiceUnavailable)
+	second.SetStatus(http.StatusServiceUnavailable)
+	err = r.Report(&message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"})
+	if err == nil || !strings.Contains(err.Error(), "all 3 alertmanagers failed") {
+		t.Errorf("expected error when every alertmanager fails, got %v", err)
+	}
+}
+
+func TestReportResend(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusOK, "")
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	r, err := CreateReporter(config.Reporter{Name: "resend", Configs: map[string]string{
+		ConfigURLs:           srv.URL,
+		ConfigResendInterval: "20ms",
+	}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	if err = r.Report(&message.Data{Level: message.LevelWarning, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send warning: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(srv.Requests()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := posts(t, srv); len(got) < 3 || got[2][0].Labels[LabelHPA] != "my-hpa" {
+		t.Fatalf("expected the firing alert to be re-sent, got %d posts", len(got))
+	}
+
+	// a reporter created on reload keeps re-sending the alerts of the previous one
+	reloaded, err := CreateReporter(config.Reporter{Name: "resend", Configs: map[string]string{ConfigURLs: srv.URL}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	if reloaded.alerts.get("test/my-hpa/"+message.AlertReplicas) == nil {
+		t.Error("expected the firing alert to be kept on reload")
+	}
+	if err = reloaded.Report(&message.Data{Level: message.LevelResolved, FiringLevel: message.LevelWarning, Alert: message.AlertReplicas,
+		Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send resolved: %v", err)
+	}
+	if alerts := r.alerts.refresh(time.Now()); len(alerts) != 0 {
+		t.Errorf("expected no firing alert after resolution, got %+v", alerts)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	reportertest.CheckValidate(t, Type, Validate, []reportertest.ValidateCase{
+		{Configs: map[string]string{ConfigURLs: "http://am-0:9093,http://am-1:9093"}},
+		{Configs: map[string]string{}, Want: []string{"configs.urls"}},
+		{Configs: map[string]string{ConfigURLs: "am-0:9093"}, Want: []string{"configs.urls"}},
+		{Configs: map[string]string{ConfigURLs: "http://am", ConfigLabels: "team"}, Want: []string{"configs.labels"}},
+		{Configs: map[string]string{ConfigURLs: "http://am", ConfigResendInterval: "0s", ConfigTimeout: "soon"}, Want: []string{"configs.resendInterval", "configs.timeout"}},
+	})
+}
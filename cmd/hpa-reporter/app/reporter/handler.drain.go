@@ -0,0 +1,64 @@
+package reporter
+
+import (
+	"errors"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ErrAbandoned is the spool error of the messages that were still queued when the drain deadline passed
+var ErrAbandoned = errors.New("abandoned at shutdown")
+
+// DrainResult counts the messages of a drain
+type DrainResult struct {
+	Delivered int
+	Failed    int
+	Abandoned int
+}
+
+// Drain delivers the queued messages of every reporter until the queues are empty or the timeout passes,
+// then stops the reporters. it must be called once.
+func (h *Handler) Drain(timeout time.Duration) DrainResult {
+	close(h.shutdown)
+	return h.drainEntries(h.set.Load().entries, timeout)
+}
+
+// drainEntries drains the reporters and stops them. the messages left at the deadline are spooled if the spool is used.
+func (h *Handler) drainEntries(entries []entry, timeout time.Duration) DrainResult {
+	var result DrainResult
+	sent := make([]int64, len(entries))
+	failed := make([]int64, len(entries))
+	for i, e := range entries {
+		sent[i], failed[i] = e.queue.sent.Load(), e.queue.failed.Load()
+		close(e.queue.drain)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+WAIT:
+	for _, e := range entries {
+		select {
+		case <-e.queue.stopped:
+		case <-deadline.C:
+			break WAIT
+		}
+	}
+	for _, e := range entries {
+		close(e.shutdown)
+	}
+
+	for i, e := range entries {
+		result.Delivered += int(e.queue.sent.Load() - sent[i])
+		result.Failed += int(e.queue.failed.Load() - failed[i])
+
+		for _, it := range e.queue.abandon() {
+			result.Abandoned++
+			logger.Warn("[reporter] message abandoned", append(messageFields(it.msg), zap.String("reporter", e.name))...)
+			h.done(e.name, it, ErrAbandoned)
+		}
+	}
+
+	return result
+}
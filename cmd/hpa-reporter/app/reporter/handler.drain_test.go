@@ -0,0 +1,83 @@
+package reporter
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/spool"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// slowReporter takes the delay for every message
+type slowReporter struct {
+	delay time.Duration
+}
+
+func (s *slowReporter) Report(_ *message.Data) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func init() {
+	Register("slow", func(cfg config.Reporter, _ chan struct{}) (Reporter, error) {
+		delay, err := time.ParseDuration(cfg.Configs["delay"])
+		if err != nil {
+			return nil, err
+		}
+		return &slowReporter{delay: delay}, nil
+	})
+}
+
+func TestDrain(t *testing.T) {
+	h, err := NewReporterHandler([]config.Reporter{
+		{Name: "a", Type: "slow", Configs: map[string]string{"delay": "5ms"}},
+		{Name: "b", Type: "slow", Configs: map[string]string{"delay": "1ms"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		h.Report(&message.Data{Name: "my-hpa", Namespace: "test"})
+	}
+
+	result := h.Drain(time.Second)
+	if result.Delivered != 10 || result.Abandoned != 0 {
+		t.Errorf("expected every message to be delivered, got %+v", result)
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	s, err := spool.Open(filepath.Join(t.TempDir(), "dead.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "slow", Configs: map[string]string{"delay": "100ms"}}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	h.UseSpool(s)
+
+	for i := 0; i < 5; i++ {
+		h.Report(&message.Data{Name: "my-hpa", Namespace: "test"})
+	}
+
+	start := time.Now()
+	result := h.Drain(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("drain took %s, expected it to stop at the deadline", elapsed)
+	}
+	if result.Delivered+result.Abandoned > 5 || result.Abandoned < 3 {
+		t.Errorf("expected the queued messages to be abandoned, got %+v", result)
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != result.Abandoned || entries[0].Error != ErrAbandoned.Error() {
+		t.Errorf("expected the abandoned messages in the spool, got %d entries", len(entries))
+	}
+}
@@ -4,51 +4,248 @@ import (
 	"fmt"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
-	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/slack"
-	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/stdout"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/spool"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ReloadDrainTimeout is how long the reporters replaced by a reload may deliver their queued messages.
+// the new reporter of the same name starts delivering after the previous one stopped.
+const ReloadDrainTimeout = 30 * time.Second
+
+// Reporter delivers a message. Report is called by a single worker per reporter,
+// so it may block until the message is delivered and returns the delivery error.
 type Reporter interface {
-	Report(msg *message.Data)
+	Report(msg *message.Data) error
+}
+
+// entry is a reporter with its configured name and its queue
+type entry struct {
+	name     string
+	cfg      config.Reporter
+	reporter Reporter
+	queue    *queue
+	shutdown chan struct{}
+}
+
+// reporterSet is the group of reporters created from one config
+type reporterSet struct {
+	entries []entry
+	// route selects the receivers of the messages without receivers, nil sends them to every reporter
+	route *route
 }
 
 // Handler is reporter handler
 type Handler struct {
-	shutdown     chan struct{}
-	ReporterList []Reporter
+	set atomic.Pointer[reporterSet]
+
+	// spool keeps the messages that failed after every retry, nil disables it
+	spool     atomic.Pointer[spool.Spool]
+	pendingMu sync.Mutex
+	pending   map[string]struct{}
+	shutdown  chan struct{}
+
+	// delivered is the last delivery time of every spool key, an older spooled message of the key is not replayed
+	deliveredMu sync.Mutex
+	delivered   map[string]time.Time
 }
 
-// NewReporterHandler creates a new reporter handler
-func NewReporterHandler(reporterConfig config.ReporterConfig) (*Handler, error) {
+// NewReporterHandler creates a new reporter handler. route may be nil to send every message to every reporter.
+func NewReporterHandler(reporters []config.Reporter, route *config.Route) (*Handler, error) {
 	h := &Handler{
-		shutdown: make(chan struct{}),
+		pending:   make(map[string]struct{}),
+		shutdown:  make(chan struct{}),
+		delivered: make(map[string]time.Time),
+	}
+
+	set, err := h.newReporterSet(reporters, route, &reporterSet{})
+	if err != nil {
+		return nil, err
+	}
+	h.set.Store(set)
+
+	return h, nil
+}
+
+// New creates a reporter that retries like the reporters of the handler.
+// it is for delivering a message directly, such as replaying the spool from the command line.
+func New(cfg config.Reporter, shutdown chan struct{}) (Reporter, error) {
+	rep, err := create(cfg, shutdown)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, cfg := range reporterConfig.Stdout {
-		rep, _ := stdout.CreateReporter(cfg, h.shutdown)
-		h.ReporterList = append(h.ReporterList, rep)
+	return newRetryReporter(cfg.Name, rep, cfg.Retry, shutdown), nil
+}
+
+// create creates the reporter with the factory of its type
+func create(cfg config.Reporter, shutdown chan struct{}) (Reporter, error) {
+	factory, ok := lookup(cfg.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown reporter type %q for reporter %q (available: %s)", cfg.Type, cfg.Name, strings.Join(Types(), ", "))
+	}
+
+	rep, err := factory(cfg, shutdown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s reporter %q: %w", cfg.Type, cfg.Name, err)
+	}
+
+	return rep, nil
+}
+
+// newReporterSet creates every reporter of the config. the reporters of prev whose config is unchanged are kept
+// with their queue, a changed reporter starts delivering after the reporter of prev with its name stopped.
+// nothing new is left running on error.
+func (h *Handler) newReporterSet(reporters []config.Reporter, routeConfig *config.Route, prev *reporterSet) (*reporterSet, error) {
+	set := &reporterSet{}
+
+	if routeConfig != nil {
+		r, err := newRoute(routeConfig, nil)
+		if err != nil {
+			return nil, fmt.Errorf("invalid route: %w", err)
+		}
+		set.route = r
 	}
 
-	for _, cfg := range reporterConfig.Slack {
-		rep, err := slack.CreateReporter(cfg, h.shutdown)
+	var created []entry
+	for _, cfg := range reporters {
+		previous, ok := prev.entry(cfg.Name)
+		if ok && reflect.DeepEqual(previous.cfg, cfg) {
+			set.entries = append(set.entries, previous)
+			continue
+		}
+
+		shutdown := make(chan struct{})
+		rep, err := create(cfg, shutdown)
 		if err != nil {
-			h.Shutdown()
-			return nil, fmt.Errorf("failed to create slack reporter: %w", err)
+			close(shutdown)
+			for _, e := range created {
+				close(e.shutdown)
+			}
+			return nil, err
 		}
-		h.ReporterList = append(h.ReporterList, rep)
+		e := entry{name: cfg.Name, cfg: cfg, reporter: rep, queue: newQueue(cfg.Name, cfg.Queue, shutdown), shutdown: shutdown}
+		e.queue.done, e.queue.dropped = h.done, h.dropped
+		created = append(created, e)
+		set.entries = append(set.entries, e)
 	}
 
-	return h, nil
+	for _, e := range created {
+		var stopped chan struct{}
+		if previous, ok := prev.entry(e.name); ok {
+			stopped = previous.queue.stopped
+		}
+		go func(e entry) {
+			// the messages of the previous reporter are delivered first, so a resolve does not overtake its trigger
+			if stopped != nil {
+				select {
+				case <-stopped:
+				case <-e.shutdown:
+				}
+			}
+			e.queue.run(newRetryReporter(e.name, e.reporter, e.cfg.Retry, e.shutdown))
+		}(e)
+	}
+
+	return set, nil
+}
+
+// entry returns the reporter of the name
+func (s *reporterSet) entry(name string) (entry, bool) {
+	for _, e := range s.entries {
+		if e.name == name {
+			return e, true
+		}
+	}
+
+	return entry{}, false
+}
+
+// Reload creates the reporters of the new config and swaps them with the running reporters.
+// a reporter whose config is unchanged keeps running with its queue and its state, the others are drained
+// in the background. the state of a removed reporter is dropped. the running reporters are kept if any new
+// reporter cannot be created.
+func (h *Handler) Reload(reporters []config.Reporter, route *config.Route) error {
+	old := h.set.Load()
+	set, err := h.newReporterSet(reporters, route, old)
+	if err != nil {
+		return err
+	}
+	h.set.Store(set)
+	dropStates(reporters)
+
+	var replaced []entry
+	for _, e := range old.entries {
+		if current, ok := set.entry(e.name); !ok || current.queue != e.queue {
+			replaced = append(replaced, e)
+		}
+	}
+	if len(replaced) == 0 {
+		return nil
+	}
+
+	go func() {
+		result := h.drainEntries(replaced, ReloadDrainTimeout)
+		logger.Info("[reporter] previous reporters drained", zap.Int("reporters", len(replaced)), zap.Int("delivered", result.Delivered),
+			zap.Int("failed", result.Failed), zap.Int("abandoned", result.Abandoned))
+	}()
+
+	return nil
 }
 
-// Report sends message to all reporters
+// Report queues the message for its receivers. the route selects the receivers, the receivers set on the message
+// by annotations or policies narrow them down. without a route the receivers of the message get it, or every reporter
+// if it has none. it does not wait for the delivery, a full queue is handled by the overflow policy of the reporter.
 func (h *Handler) Report(msg *message.Data) {
-	for _, rep := range h.ReporterList {
-		go rep.Report(msg)
+	set := h.set.Load()
+
+	receivers := msg.Receivers
+	if set.route != nil {
+		routed := set.route.receiversOf(msg)
+		if len(receivers) > 0 {
+			// a route that does not match the level or the hpa still stops the message
+			var both []string
+			for _, r := range routed {
+				if contains(receivers, r) {
+					both = append(both, r)
+				}
+			}
+			routed = both
+		}
+		if len(routed) == 0 {
+			logger.Debug("[reporter] no route matched the message", append(messageFields(msg), zap.Strings("receivers", receivers))...)
+			return
+		}
+		receivers = routed
+	}
+
+	var sent int
+	for _, e := range set.entries {
+		if len(receivers) > 0 && !contains(receivers, e.name) {
+			continue
+		}
+		e.queue.push(item{msg: msg})
+		sent++
+	}
+
+	if sent == 0 && len(receivers) > 0 {
+		logger.Warn("[reporter] no reporter matched the receivers", zap.String("name", msg.Name),
+			zap.String("namespace", msg.Namespace), zap.Strings("receivers", receivers))
 	}
 }
 
-// Shutdown stops all reporters
-func (h *Handler) Shutdown() {
-	close(h.shutdown)
+// contains reports whether s is in list
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,160 @@
+package reporter
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/spool"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"time"
+)
+
+// UseSpool stores the messages that fail after every retry in the spool
+func (h *Handler) UseSpool(s *spool.Spool) {
+	h.spool.Store(s)
+}
+
+// done is called by the queue worker with the delivery result of the item
+func (h *Handler) done(name string, it item, err error) {
+	s := h.spool.Load()
+	if s == nil {
+		return
+	}
+	if it.spoolID != "" {
+		defer h.unpend(it.spoolID)
+	}
+	if err == nil && it.spoolID == "" {
+		h.deliveredMu.Lock()
+		h.delivered[spool.Key(name, it.msg)] = time.Now()
+		h.deliveredMu.Unlock()
+	}
+
+	switch {
+	case err == nil && it.spoolID != "":
+		if _, err = s.Remove(it.spoolID); err != nil {
+			logger.Error("[reporter] failed to remove delivered message from spool", zap.String("id", it.spoolID), zap.Error(err))
+			return
+		}
+		logger.Info("[reporter] spooled message delivered", append(messageFields(it.msg), zap.String("reporter", name), zap.String("id", it.spoolID))...)
+	case err != nil && it.spoolID != "":
+		if err = s.Failed(it.spoolID, err); err != nil {
+			logger.Error("[reporter] failed to update spool", zap.String("id", it.spoolID), zap.Error(err))
+		}
+	case err != nil:
+		entry, spoolErr := s.Add(name, it.msg, err)
+		if spoolErr != nil {
+			logger.Error("[reporter] failed to spool message, the message is lost", append(messageFields(it.msg), zap.String("reporter", name), zap.Error(spoolErr))...)
+			return
+		}
+		logger.Info("[reporter] message spooled", append(messageFields(it.msg), zap.String("reporter", name), zap.String("id", entry.ID))...)
+	}
+}
+
+// dropped is called by the queue for a spooled item that did not fit, it stays in the spool for the next run
+func (h *Handler) dropped(it item) {
+	if it.spoolID != "" {
+		h.unpend(it.spoolID)
+	}
+}
+
+// unpend allows the spool entry to be queued again
+func (h *Handler) unpend(id string) {
+	h.pendingMu.Lock()
+	delete(h.pending, id)
+	h.pendingMu.Unlock()
+}
+
+// RunSpool delivers the spooled messages again every retry interval until the handler is shut down
+func (h *Handler) RunSpool(cfg config.SpoolConfig) {
+	cfg = cfg.WithDefaults()
+	ticker := time.NewTicker(cfg.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.replaySpool(cfg.MaxAge)
+		case <-h.shutdown:
+			return
+		}
+	}
+}
+
+// replaySpool removes the expired and the superseded entries and queues the others for their reporter.
+// an entry is superseded by a newer entry or delivery of the same alert, so an old firing message
+// does not reopen an alert that was resolved since.
+func (h *Handler) replaySpool(maxAge time.Duration) {
+	s := h.spool.Load()
+	if s == nil {
+		return
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		logger.Error("[reporter] failed to read spool", zap.Error(err))
+		return
+	}
+	latest := h.latest(entries, maxAge)
+
+	now := time.Now()
+	if _, err = s.Purge(func(e spool.Entry) bool {
+		if now.Sub(e.FailedAt) >= maxAge {
+			logger.Warn("[reporter] spooled message expired", append(messageFields(e.Message), zap.String("reporter", e.Reporter),
+				zap.String("id", e.ID), zap.Int("attempts", e.Attempts), zap.String("error", e.Error))...)
+			return true
+		}
+		if e.FailedAt.Before(latest[spool.Key(e.Reporter, e.Message)]) {
+			logger.Info("[reporter] spooled message superseded by a newer message", append(messageFields(e.Message),
+				zap.String("reporter", e.Reporter), zap.String("id", e.ID))...)
+			return true
+		}
+		return false
+	}); err != nil {
+		logger.Error("[reporter] failed to purge expired messages from spool", zap.Error(err))
+		return
+	}
+
+	if entries, err = s.List(); err != nil {
+		logger.Error("[reporter] failed to read spool", zap.Error(err))
+		return
+	}
+
+	set := h.set.Load()
+	for _, e := range entries {
+		target, ok := set.entry(e.Reporter)
+		if !ok {
+			logger.Debug("[reporter] spooled message has no reporter", zap.String("reporter", e.Reporter), zap.String("id", e.ID))
+			continue
+		}
+
+		h.pendingMu.Lock()
+		_, pending := h.pending[e.ID]
+		h.pending[e.ID] = struct{}{}
+		h.pendingMu.Unlock()
+		if pending {
+			continue
+		}
+
+		target.queue.push(item{msg: e.Message, spoolID: e.ID})
+	}
+}
+
+// latest returns the time of the newest spooled or delivered message of every key.
+// the deliveries older than maxAge cannot supersede a spooled entry any more and are dropped.
+func (h *Handler) latest(entries []spool.Entry, maxAge time.Duration) map[string]time.Time {
+	latest := spool.Latest(entries)
+
+	h.deliveredMu.Lock()
+	defer h.deliveredMu.Unlock()
+
+	for key, t := range h.delivered {
+		if time.Since(t) >= maxAge {
+			delete(h.delivered, key)
+			continue
+		}
+		if t.After(latest[key]) {
+			latest[key] = t
+		}
+	}
+
+	return latest
+}
@@ -0,0 +1,193 @@
+package reporter
+
+import (
+	"errors"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/spool"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyReporter fails until it is healthy
+type flakyReporter struct {
+	healthy   atomic.Bool
+	delivered chan *message.Data
+}
+
+func (f *flakyReporter) Report(msg *message.Data) error {
+	if !f.healthy.Load() {
+		return errors.New("endpoint is down")
+	}
+	f.delivered <- msg
+	return nil
+}
+
+var flaky = &flakyReporter{delivered: make(chan *message.Data, 1)}
+
+func init() {
+	Register("flaky", func(cfg config.Reporter, _ chan struct{}) (Reporter, error) {
+		return flaky, nil
+	})
+}
+
+func TestHandlerSpool(t *testing.T) {
+	flaky.healthy.Store(false)
+	s, err := spool.Open(filepath.Join(t.TempDir(), "dead.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "flaky"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+	h.UseSpool(s)
+
+	h.Report(&message.Data{Name: "my-hpa", Namespace: "test"})
+	entries := waitEntries(t, s, 1)
+	if entries[0].Reporter != "a" || entries[0].Message.Name != "my-hpa" {
+		t.Fatalf("unexpected spool entry %+v", entries[0])
+	}
+
+	flaky.healthy.Store(true)
+	h.replaySpool(time.Hour)
+	select {
+	case msg := <-flaky.delivered:
+		if msg.Name != "my-hpa" {
+			t.Errorf("unexpected replayed message %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the spooled message to be replayed")
+	}
+	waitEntries(t, s, 0)
+}
+
+func TestHandlerSpoolExpired(t *testing.T) {
+	s, _ := spool.Open(filepath.Join(t.TempDir(), "dead.jsonl"))
+	if _, err := s.Add("a", &message.Data{Name: "my-hpa"}, errors.New("down")); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewReporterHandler(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Drain(0)
+	h.UseSpool(s)
+
+	time.Sleep(time.Millisecond)
+	h.replaySpool(time.Nanosecond)
+	waitEntries(t, s, 0)
+}
+
+func TestHandlerSpoolSuperseded(t *testing.T) {
+	flaky.healthy.Store(true)
+	s, _ := spool.Open(filepath.Join(t.TempDir(), "dead.jsonl"))
+
+	firing := &message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+	resolved := &message.Data{Level: message.LevelResolved, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+	other := &message.Data{Level: message.LevelWarning, Alert: message.AlertScalingLimited, Name: "my-hpa", Namespace: "test"}
+	for _, msg := range []*message.Data{firing, other, resolved} {
+		if _, err := s.Add("a", msg, errors.New("down")); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	h, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "flaky"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+	h.UseSpool(s)
+
+	// the firing message is older than the resolved message of the same alert, only the newest of each alert is replayed
+	h.replaySpool(time.Hour)
+	var replayed []string
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-flaky.delivered:
+			replayed = append(replayed, msg.Alert+"/"+msg.Level)
+		case <-time.After(time.Second):
+			t.Fatalf("expected 2 replayed messages, got %v", replayed)
+		}
+	}
+	if replayed[0] != message.AlertScalingLimited+"/"+message.LevelWarning || replayed[1] != message.AlertReplicas+"/"+message.LevelResolved {
+		t.Errorf("unexpected replayed messages %v", replayed)
+	}
+	waitEntries(t, s, 0)
+	select {
+	case msg := <-flaky.delivered:
+		t.Errorf("expected the superseded message not to be replayed, got %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestHandlerSpoolSupersededByDelivery(t *testing.T) {
+	flaky.healthy.Store(true)
+	s, _ := spool.Open(filepath.Join(t.TempDir(), "dead.jsonl"))
+
+	msg := &message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+	if _, err := s.Add("a", msg, errors.New("down")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	h, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "flaky"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+	h.UseSpool(s)
+
+	h.Report(&message.Data{Level: message.LevelResolved, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"})
+	select {
+	case <-flaky.delivered:
+	case <-time.After(time.Second):
+		t.Fatal("expected the resolved message to be delivered")
+	}
+
+	// the done callback runs right after the delivery
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.deliveredMu.Lock()
+		n := len(h.delivered)
+		h.deliveredMu.Unlock()
+		if n == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	h.replaySpool(time.Hour)
+	waitEntries(t, s, 0)
+	select {
+	case msg := <-flaky.delivered:
+		t.Errorf("expected the spooled firing message not to be replayed after the resolved delivery, got %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// waitEntries waits until the spool has n entries
+func waitEntries(t *testing.T, s *spool.Spool, n int) []spool.Entry {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		entries, err := s.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) == n {
+			return entries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d spool entries, got %d", n, len(entries))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
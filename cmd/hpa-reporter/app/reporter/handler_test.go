@@ -0,0 +1,215 @@
+package reporter
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeReporter struct {
+	name     string
+	received chan *message.Data
+}
+
+func (f *fakeReporter) Report(msg *message.Data) error {
+	f.received <- msg
+	return nil
+}
+
+func init() {
+	Register("fake", func(cfg config.Reporter, _ chan struct{}) (Reporter, error) {
+		return &fakeReporter{name: cfg.Name, received: make(chan *message.Data, 1)}, nil
+	})
+}
+
+func TestNewReporterHandler(t *testing.T) {
+	h, err := NewReporterHandler([]config.Reporter{
+		{Name: "a", Type: "fake"},
+		{Name: "b", Type: "fake"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+
+	if entries := h.set.Load().entries; len(entries) != 2 {
+		t.Errorf("expected 2 reporters, got %d", len(entries))
+	}
+}
+
+func TestNewReporterHandlerUnknownType(t *testing.T) {
+	_, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "unknown"}}, nil)
+	if err == nil {
+		t.Fatal("expected error for unknown reporter type")
+	}
+	if !strings.Contains(err.Error(), `"unknown"`) || !strings.Contains(err.Error(), "fake") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	problems := Validate([]config.Reporter{
+		{Name: "a", Type: "fake"},
+		{Name: "b", Type: "unknown"},
+	})
+	if len(problems) != 1 || problems[0].Path != "reporters[1].type" {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestValidateReferences(t *testing.T) {
+	RegisterValidator("fake", func(cfg config.Reporter) []config.Problem {
+		var problems []config.Problem
+		for _, key := range []string{"url", "timeout"} {
+			if v := cfg.Configs[key]; v == "" || v == "${env:TIMEOUT}" {
+				problems = append(problems, config.Problem{Path: "configs." + key, Message: "invalid"})
+			}
+		}
+		return problems
+	})
+	defer func() {
+		factoriesMu.Lock()
+		delete(validators, "fake")
+		factoriesMu.Unlock()
+	}()
+
+	problems := Validate([]config.Reporter{{
+		Name:       "a",
+		Type:       "fake",
+		Configs:    map[string]string{"timeout": "${env:TIMEOUT}"},
+		SecretRefs: map[string]config.SecretRef{"url": {Name: "slack", Key: "url"}},
+	}})
+	if len(problems) != 0 {
+		t.Errorf("referenced values should not be validated: %v", problems)
+	}
+}
+
+func TestReportReceivers(t *testing.T) {
+	h, err := NewReporterHandler([]config.Reporter{
+		{Name: "a", Type: "fake"},
+		{Name: "b", Type: "fake"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+
+	h.Report(&message.Data{Name: "my-hpa", Receivers: []string{"b"}})
+
+	select {
+	case <-h.set.Load().entries[1].reporter.(*fakeReporter).received:
+	case <-time.After(time.Second):
+		t.Fatal("expected receiver b to get the message")
+	}
+	select {
+	case msg := <-h.set.Load().entries[0].reporter.(*fakeReporter).received:
+		t.Errorf("expected reporter a not to get the message, got %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestReload(t *testing.T) {
+	h, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "fake"}, {Name: "b", Type: "fake"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+	old := h.set.Load()
+
+	if err = h.Reload([]config.Reporter{{Name: "b", Type: "fake"}, {Name: "c", Type: "unknown"}}, nil); err == nil {
+		t.Fatal("expected error for unknown reporter type")
+	}
+	if h.set.Load() != old {
+		t.Fatal("expected running reporters to be kept on error")
+	}
+
+	if err = h.Reload([]config.Reporter{
+		{Name: "b", Type: "fake"},
+		{Name: "c", Type: "fake", Configs: map[string]string{"url": "http://c"}},
+	}, nil); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	entries := h.set.Load().entries
+	if len(entries) != 2 || entries[0].name != "b" || entries[1].name != "c" {
+		t.Fatalf("unexpected reporters after reload: %+v", entries)
+	}
+	// an unchanged reporter keeps running with its queue
+	if entries[0].queue != old.entries[1].queue || entries[0].reporter != old.entries[1].reporter {
+		t.Error("expected the unchanged reporter to be kept")
+	}
+	// the removed reporter is drained in the background
+	select {
+	case <-old.entries[0].shutdown:
+	case <-time.After(time.Second):
+		t.Error("expected the removed reporter to be shut down")
+	}
+	select {
+	case <-entries[0].shutdown:
+		t.Error("expected the unchanged reporter to keep running")
+	default:
+	}
+}
+
+// orderedReporter records the messages of every reporter created with the type in delivery order
+type orderedReporter struct {
+	id    string
+	delay time.Duration
+}
+
+var (
+	orderedMu  sync.Mutex
+	orderedLog []string
+)
+
+func (o *orderedReporter) Report(msg *message.Data) error {
+	time.Sleep(o.delay)
+	orderedMu.Lock()
+	orderedLog = append(orderedLog, o.id+"/"+msg.Name)
+	orderedMu.Unlock()
+	return nil
+}
+
+func init() {
+	Register("ordered", func(cfg config.Reporter, _ chan struct{}) (Reporter, error) {
+		delay, _ := time.ParseDuration(cfg.Configs["delay"])
+		return &orderedReporter{id: cfg.Configs["id"], delay: delay}, nil
+	})
+}
+
+func TestReloadOrder(t *testing.T) {
+	orderedMu.Lock()
+	orderedLog = nil
+	orderedMu.Unlock()
+
+	h, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "ordered", Configs: map[string]string{"id": "old", "delay": "20ms"}}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+
+	for _, name := range []string{"trigger-1", "trigger-2", "trigger-3"} {
+		h.Report(&message.Data{Name: name})
+	}
+	if err = h.Reload([]config.Reporter{{Name: "a", Type: "ordered", Configs: map[string]string{"id": "new"}}}, nil); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	h.Report(&message.Data{Name: "resolve"})
+
+	deadline := time.Now().Add(time.Second)
+	var got []string
+	for len(got) < 4 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		orderedMu.Lock()
+		got = append([]string(nil), orderedLog...)
+		orderedMu.Unlock()
+	}
+
+	// the changed reporter delivers after the previous one delivered its queue
+	want := "old/trigger-1,old/trigger-2,old/trigger-3,new/resolve"
+	if strings.Join(got, ",") != want {
+		t.Errorf("expected %s, got %v", want, got)
+	}
+}
@@ -0,0 +1,60 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxResponseSize limits the response body that is decoded
+const maxResponseSize = 64 * 1024
+
+// PostJSON posts in as json to the url with the headers and decodes the response into out if it is not nil
+func PostJSON(client *http.Client, url string, header map[string]string, in, out interface{}) error {
+	return SendJSON(client, http.MethodPost, url, header, in, out)
+}
+
+// SendJSON sends in as json with the method to the url and decodes the response into out if it is not nil
+func SendJSON(client *http.Client, method, url string, header map[string]string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	return Send(client, req, out)
+}
+
+// Send sends the request and decodes the json response into out if it is not nil.
+// a non-2xx response is returned as *HTTPError, so the retry reporter can tell if it is worth sending again.
+func Send(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return NewHTTPError(resp)
+	}
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package reporter
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPostJSON(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("unexpected headers: %v", r.Header)
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		OK bool `json:"ok"`
+	}
+	if err := PostJSON(srv.Client(), srv.URL, map[string]string{"Authorization": "Bearer token"}, map[string]string{"a": "b"}, &out); err != nil {
+		t.Fatalf("failed to post: %v", err)
+	}
+	if !out.OK {
+		t.Error("expected the response to be decoded")
+	}
+}
+
+func TestPostJSONNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		http.Error(w, "throttled", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	var httpErr *HTTPError
+	err := PostJSON(srv.Client(), srv.URL, nil, struct{}{}, nil)
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests || httpErr.RetryAfter != 2*time.Second || httpErr.Body != "throttled" {
+		t.Fatalf("expected http error, got %v", err)
+	}
+	if ok, _ := retryable(err); !ok {
+		t.Error("expected 429 to be retryable")
+	}
+}
@@ -0,0 +1,101 @@
+package opsgenie
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"strings"
+)
+
+const (
+	Source = "hpa-reporter"
+
+	PriorityCritical = "P1"
+	PriorityWarning  = "P3"
+	PriorityDefault  = "P5"
+
+	// maxMessageLength is the message limit of the alert api, longer messages are cut
+	maxMessageLength = 130
+)
+
+type (
+	// alert is the create alert request
+	alert struct {
+		Message     string            `json:"message"`
+		Alias       string            `json:"alias"`
+		Description string            `json:"description,omitempty"`
+		Tags        []string          `json:"tags,omitempty"`
+		Details     map[string]string `json:"details,omitempty"`
+		Entity      string            `json:"entity,omitempty"`
+		Source      string            `json:"source"`
+		Priority    string            `json:"priority"`
+	}
+
+	// priorityRequest is the update priority request
+	priorityRequest struct {
+		Priority string `json:"priority"`
+	}
+
+	// closeRequest is the close alert request
+	closeRequest struct {
+		Source string `json:"source"`
+		Note   string `json:"note,omitempty"`
+	}
+)
+
+// newAlert builds the create alert request of a firing message
+func (r *Reporter) newAlert(alias, priority string, msg *message.Data) *alert {
+	text := fmt.Sprintf("HPA %s/%s %s: %s", msg.Namespace, msg.Name, strings.ToUpper(msg.Level), msg.Summary())
+	if len(text) > maxMessageLength {
+		text = text[:maxMessageLength]
+	}
+
+	tags := []string{"namespace:" + msg.Namespace, "name:" + msg.Name}
+	if r.cluster != "" {
+		tags = append(tags, "cluster:"+r.cluster)
+	}
+	tags = append(tags, r.tags...)
+
+	details := map[string]string{
+		"namespace":       msg.Namespace,
+		"name":            msg.Name,
+		"level":           msg.Level,
+		"alert":           msg.Alert,
+		"currentReplicas": fmt.Sprint(msg.CurrentReplicas),
+		"desiredReplicas": fmt.Sprint(msg.DesiredReplicas),
+		"maxReplicas":     fmt.Sprint(msg.MaxReplicas),
+	}
+	if r.cluster != "" {
+		details["cluster"] = r.cluster
+	}
+	if msg.TargetName != "" {
+		details["workload"] = msg.TargetKind + "/" + msg.TargetName
+	}
+	if msg.Time != "" {
+		details["lastScaleTime"] = msg.Time
+	}
+
+	description := fmt.Sprintf("replicas %d/%d (desired %d)", msg.CurrentReplicas, msg.MaxReplicas, msg.DesiredReplicas)
+	if msg.ConditionReason != "" {
+		description += fmt.Sprintf("\n%s: %s", msg.ConditionReason, msg.ConditionMessage)
+	}
+
+	return &alert{
+		Message:     text,
+		Alias:       alias,
+		Description: description,
+		Tags:        tags,
+		Details:     details,
+		Entity:      msg.Namespace + "/" + msg.Name,
+		Source:      Source,
+		Priority:    priority,
+	}
+}
+
+// aliasOf identifies the alert of an hpa, opsgenie updates the open alert with the same alias
+func aliasOf(cluster string, msg *message.Data) string {
+	if cluster == "" {
+		return msg.Namespace + "/" + msg.Name
+	}
+
+	return cluster + "/" + msg.Namespace + "/" + msg.Name
+}
@@ -0,0 +1,193 @@
+package opsgenie
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+func init() {
+	reporter.Register(Type, func(cfg config.Reporter, _ chan struct{}) (reporter.Reporter, error) {
+		r, err := CreateReporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+	reporter.RegisterValidator(Type, Validate)
+}
+
+const (
+	Type = "opsgenie"
+
+	ConfigAPIKey  = "apiKey"
+	ConfigURL     = "url"
+	ConfigCluster = "cluster"
+	ConfigTags    = "tags"
+	ConfigTimeout = "timeout"
+
+	// DefaultURL is the api of the us region, eu accounts use https://api.eu.opsgenie.com
+	DefaultURL     = "https://api.opsgenie.com"
+	DefaultTimeout = 5 * time.Second
+)
+
+// Reporter creates opsgenie alerts and closes them when the hpa recovers
+type Reporter struct {
+	name    string
+	url     string
+	apiKey  string
+	cluster string
+	tags    []string
+	client  *http.Client
+
+	// alerts is keyed by alias, it is kept across config reloads.
+	// it is only accessed by the single queue worker of the reporter.
+	alerts map[string]*openAlert
+}
+
+// openAlert is the open alert of an hpa
+type openAlert struct {
+	// firing is the level of every alert kind of the hpa that is not resolved yet
+	firing map[string]string
+	// priority is the priority of the alert in opsgenie, empty until the alert is created
+	priority string
+}
+
+// Report creates the alert of a firing message and keeps its priority at the highest firing level.
+// the alert is closed when the last alert kind of the hpa is resolved, the collector resolves every alert kind on its own.
+func (r *Reporter) Report(msg *message.Data) error {
+	alias := aliasOf(r.cluster, msg)
+	a, ok := r.alerts[alias]
+
+	if msg.Level == message.LevelResolved {
+		if !ok {
+			// the other alert kinds of an unknown alert may still fire, it is not closed blindly
+			logger.Debug("opsgenie alert is not known, skip close", zap.String("reporter", r.name), zap.String("alias", alias))
+			return nil
+		}
+		delete(a.firing, msg.Alert)
+		if len(a.firing) > 0 {
+			// another alert kind of the hpa still fires
+			return r.setPriority(alias, a)
+		}
+		path := "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		if err := r.send(http.MethodPost, path, &closeRequest{Source: Source, Note: fmt.Sprintf("resolved after %s", msg.Duration.Round(time.Second))}); err != nil {
+			return err
+		}
+		// the alert is forgotten once the close is accepted, a retry still finds it
+		delete(r.alerts, alias)
+
+		return nil
+	}
+
+	if !ok {
+		a = &openAlert{firing: make(map[string]string)}
+		r.alerts[alias] = a
+	}
+	a.firing[msg.Alert] = msg.Level
+
+	// opsgenie only counts a create request of an open alias, the priority is updated on its own.
+	// it is updated before the create, so a retry after a failed update does not create the alert twice.
+	if err := r.setPriority(alias, a); err != nil {
+		return err
+	}
+	if err := r.send(http.MethodPost, "/v2/alerts", r.newAlert(alias, a.highest(), msg)); err != nil {
+		return err
+	}
+	if a.priority == "" {
+		a.priority = a.highest()
+	}
+
+	return nil
+}
+
+// setPriority updates the priority of the open alert if the highest firing level changed
+func (r *Reporter) setPriority(alias string, a *openAlert) error {
+	priority := a.highest()
+	if a.priority == "" || a.priority == priority {
+		return nil
+	}
+
+	path := "/v2/alerts/" + url.PathEscape(alias) + "/priority?identifierType=alias"
+	if err := r.send(http.MethodPut, path, &priorityRequest{Priority: priority}); err != nil {
+		return err
+	}
+	a.priority = priority
+
+	return nil
+}
+
+// send sends the request body to the path of the alert api
+func (r *Reporter) send(method, path string, v interface{}) error {
+	return reporter.SendJSON(r.client, method, r.url+path, map[string]string{"Authorization": "GenieKey " + r.apiKey}, v, nil)
+}
+
+// highest returns the priority of the highest firing level
+func (a *openAlert) highest() string {
+	priority := PriorityDefault
+	for _, level := range a.firing {
+		switch {
+		case level == message.LevelCritical:
+			return PriorityCritical
+		case level == message.LevelWarning:
+			priority = PriorityWarning
+		}
+	}
+
+	return priority
+}
+
+// Validate checks the opsgenie reporter config
+func Validate(cfg config.Reporter) []config.Problem {
+	var problems []config.Problem
+
+	if cfg.Configs[ConfigAPIKey] == "" {
+		problems = append(problems, config.Problem{Path: "configs." + ConfigAPIKey, Message: fmt.Sprintf("%s is required", ConfigAPIKey)})
+	}
+
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			problems = append(problems, config.Problem{Path: "configs." + ConfigTimeout, Message: fmt.Sprintf("invalid duration %q", v)})
+		}
+	}
+
+	return problems
+}
+
+// CreateReporter creates a new opsgenie reporter
+func CreateReporter(cfg config.Reporter) (*Reporter, error) {
+	if problems := Validate(cfg); len(problems) > 0 {
+		return nil, fmt.Errorf("opsgenie reporter(%s): %w", cfg.Name, &config.ValidationError{Problems: problems})
+	}
+
+	timeout := DefaultTimeout
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		timeout, _ = time.ParseDuration(v)
+	}
+
+	r := &Reporter{
+		name:    cfg.Name,
+		alerts:  reporter.State(cfg, func() map[string]*openAlert { return make(map[string]*openAlert) }),
+		url:     strings.TrimSuffix(cfg.Configs[ConfigURL], "/"),
+		apiKey:  cfg.Configs[ConfigAPIKey],
+		cluster: cfg.Configs[ConfigCluster],
+		client:  &http.Client{Timeout: timeout},
+	}
+	if r.url == "" {
+		r.url = DefaultURL
+	}
+	for _, tag := range strings.Split(cfg.Configs[ConfigTags], ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			r.tags = append(r.tags, tag)
+		}
+	}
+
+	return r, nil
+}
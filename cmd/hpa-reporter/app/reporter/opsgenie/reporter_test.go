@@ -0,0 +1,219 @@
+package opsgenie
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/reportertest"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calls returns the method and path of the requests to the server
+func calls(srv *reportertest.Server) []string {
+	var result []string
+	for _, req := range srv.Requests() {
+		result = append(result, req.Method+" "+req.Path)
+	}
+
+	return result
+}
+
+func TestReportLifecycle(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusAccepted, `{"result":"Request will be processed","took":0.1,"requestId":"43a29c5c"}`)
+	r, err := CreateReporter(config.Reporter{Name: "lifecycle", Configs: map[string]string{
+		ConfigAPIKey:  "test-key",
+		ConfigURL:     srv.URL + "/",
+		ConfigCluster: "prod-1",
+		ConfigTags:    "team:payments, hpa",
+	}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	hpa := func(alert, level string) *message.Data {
+		return &message.Data{Level: level, Alert: alert, Name: "my-hpa", Namespace: "test", TargetKind: "Deployment", TargetName: "my-app",
+			MaxReplicas: 10, Duration: 2 * time.Minute}
+	}
+	steps := []*message.Data{
+		hpa(message.AlertReplicas, message.LevelWarning),
+		hpa(message.AlertScalingLimited, message.LevelWarning),
+		// the warning escalates, the open alert becomes P1
+		hpa(message.AlertReplicas, message.LevelCritical),
+		// Replicas still fires, the alert stays open
+		hpa(message.AlertScalingLimited, message.LevelResolved),
+		hpa(message.AlertReplicas, message.LevelResolved),
+	}
+	for _, msg := range steps {
+		if err = r.Report(msg); err != nil {
+			t.Fatalf("failed to report %s %s: %v", msg.Alert, msg.Level, err)
+		}
+	}
+
+	const alias = "/v2/alerts/prod-1%2Ftest%2Fmy-hpa"
+	want := []string{
+		"POST /v2/alerts",
+		"POST /v2/alerts",
+		"PUT " + alias + "/priority?identifierType=alias",
+		"POST /v2/alerts",
+		"POST " + alias + "/close?identifierType=alias",
+	}
+	if got := calls(srv); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected requests %v, got %v", want, got)
+	}
+
+	requests := srv.Requests()
+	for _, req := range requests {
+		if req.Header.Get("Authorization") != "GenieKey test-key" {
+			t.Errorf("unexpected authorization header: %s", req.Header.Get("Authorization"))
+		}
+	}
+
+	var created alert
+	requests[0].Decode(t, &created)
+	if created.Alias != "prod-1/test/my-hpa" || created.Priority != PriorityWarning || created.Details["workload"] != "Deployment/my-app" {
+		t.Errorf("unexpected alert: %+v", created)
+	}
+	if tags := strings.Join(created.Tags, ","); tags != "namespace:test,name:my-hpa,cluster:prod-1,team:payments,hpa" {
+		t.Errorf("unexpected tags: %s", tags)
+	}
+
+	var priority priorityRequest
+	requests[2].Decode(t, &priority)
+	if priority.Priority != PriorityCritical {
+		t.Errorf("expected priority %s, got %s", PriorityCritical, priority.Priority)
+	}
+
+	var closed closeRequest
+	requests[4].Decode(t, &closed)
+	if closed.Source != Source || !strings.Contains(closed.Note, "2m0s") {
+		t.Errorf("unexpected close request: %+v", closed)
+	}
+}
+
+func TestReportRetryCreate(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusServiceUnavailable, "")
+	r, err := CreateReporter(config.Reporter{Name: "retry-create", Configs: map[string]string{ConfigAPIKey: "key", ConfigURL: srv.URL}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	msg := &message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+	if err = r.Report(msg); err == nil {
+		t.Fatal("expected error from unavailable api")
+	}
+
+	// the retry creates the alert again instead of updating the priority of an alert that does not exist
+	srv.SetStatus(http.StatusAccepted)
+	if err = r.Report(msg); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if got := calls(srv); strings.Join(got, ",") != "POST /v2/alerts,POST /v2/alerts" {
+		t.Errorf("unexpected requests %v", got)
+	}
+}
+
+func TestReportRetryPriority(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusAccepted, "")
+	r, err := CreateReporter(config.Reporter{Name: "retry-priority", Configs: map[string]string{ConfigAPIKey: "key", ConfigURL: srv.URL}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	warning := &message.Data{Level: message.LevelWarning, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+	if err = r.Report(warning); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+
+	// the failed priority update is retried without creating the alert twice
+	srv.SetStatus(http.StatusServiceUnavailable)
+	critical := &message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+	if err = r.Report(critical); err == nil {
+		t.Fatal("expected error from unavailable api")
+	}
+	srv.SetStatus(http.StatusAccepted)
+	if err = r.Report(critical); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if err = r.Report(&message.Data{Level: message.LevelResolved, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+
+	const alias = "/v2/alerts/test%2Fmy-hpa"
+	want := []string{"POST /v2/alerts", "PUT " + alias + "/priority?identifierType=alias", "PUT " + alias + "/priority?identifierType=alias",
+		"POST /v2/alerts", "POST " + alias + "/close?identifierType=alias"}
+	if got := calls(srv); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected requests %v, got %v", want, got)
+	}
+}
+
+func TestReportReload(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusAccepted, "")
+	cfg := config.Reporter{Name: "reload", Type: Type, Configs: map[string]string{ConfigAPIKey: "key", ConfigURL: srv.URL}}
+	r, err := CreateReporter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	hpa := func(alert, level string) *message.Data {
+		return &message.Data{Level: level, Alert: alert, Name: "my-hpa", Namespace: "test"}
+	}
+	for _, msg := range []*message.Data{hpa(message.AlertReplicas, message.LevelWarning), hpa(message.AlertScalingLimited, message.LevelWarning)} {
+		if err = r.Report(msg); err != nil {
+			t.Fatalf("failed to report: %v", err)
+		}
+	}
+
+	// the reporter created on reload knows that Replicas still fires
+	cfg.Configs[ConfigTimeout] = "10s"
+	reloaded, err := CreateReporter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	for _, msg := range []*message.Data{hpa(message.AlertScalingLimited, message.LevelResolved), hpa(message.AlertReplicas, message.LevelResolved)} {
+		if err = reloaded.Report(msg); err != nil {
+			t.Fatalf("failed to report: %v", err)
+		}
+	}
+
+	want := "POST /v2/alerts,POST /v2/alerts,POST /v2/alerts/test%2Fmy-hpa/close?identifierType=alias"
+	if got := calls(srv); strings.Join(got, ",") != want {
+		t.Errorf("expected the alert to be closed after the last alert kind, got %v", got)
+	}
+
+	// an alert that is not known is not closed, its other alert kinds may still fire
+	if err = reloaded.Report(hpa(message.AlertReplicas, message.LevelResolved)); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if got := calls(srv); len(got) != 3 {
+		t.Errorf("expected no close of an unknown alert, got %v", got)
+	}
+}
+
+func TestNewAlert(t *testing.T) {
+	r, err := CreateReporter(config.Reporter{Name: "new-alert", Configs: map[string]string{ConfigAPIKey: "key"}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	if r.url != DefaultURL {
+		t.Errorf("expected default url %s, got %s", DefaultURL, r.url)
+	}
+
+	msg := &message.Data{Level: message.LevelWarning, Name: strings.Repeat("a", 200), Namespace: "test"}
+	a := r.newAlert(aliasOf(r.cluster, msg), PriorityWarning, msg)
+	if a.Priority != PriorityWarning || a.Alias != "test/"+msg.Name {
+		t.Errorf("unexpected alert: %+v", a)
+	}
+	if len(a.Message) != maxMessageLength {
+		t.Errorf("expected message cut to %d, got %d", maxMessageLength, len(a.Message))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	reportertest.CheckValidate(t, Type, Validate, []reportertest.ValidateCase{
+		{Configs: map[string]string{ConfigAPIKey: "key"}},
+		{Configs: map[string]string{}, Want: []string{"configs.apiKey"}},
+		{Configs: map[string]string{ConfigAPIKey: "key", ConfigTimeout: "soon"}, Want: []string{"configs.timeout"}},
+	})
+}
@@ -0,0 +1,95 @@
+package pagerduty
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"strings"
+)
+
+const (
+	ActionTrigger = "trigger"
+	ActionResolve = "resolve"
+
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+type (
+	// event is a pagerduty events api v2 event
+	event struct {
+		RoutingKey  string   `json:"routing_key"`
+		EventAction string   `json:"event_action"`
+		DedupKey    string   `json:"dedup_key"`
+		Payload     *payload `json:"payload,omitempty"`
+	}
+
+	// payload describes the triggered alert
+	payload struct {
+		Summary       string                 `json:"summary"`
+		Source        string                 `json:"source"`
+		Severity      string                 `json:"severity"`
+		Component     string                 `json:"component,omitempty"`
+		Group         string                 `json:"group,omitempty"`
+		Class         string                 `json:"class,omitempty"`
+		CustomDetails map[string]interface{} `json:"custom_details,omitempty"`
+	}
+)
+
+// newTrigger builds the trigger event of a firing message with the severity of the incident
+func (r *Reporter) newTrigger(key, severity string, msg *message.Data) *event {
+	return &event{
+		RoutingKey:  r.routingKey,
+		EventAction: ActionTrigger,
+		DedupKey:    key,
+		Payload: &payload{
+			Summary:       fmt.Sprintf("HPA %s/%s %s: %s", msg.Namespace, msg.Name, strings.ToUpper(msg.Level), msg.Summary()),
+			Source:        r.source,
+			Severity:      severity,
+			Component:     msg.Namespace + "/" + msg.Name,
+			Group:         msg.Namespace,
+			Class:         msg.Alert,
+			CustomDetails: customDetails(r.cluster, msg),
+		},
+	}
+}
+
+// dedupKey identifies the incident of an hpa, every alert of the hpa updates the same incident
+func dedupKey(cluster string, msg *message.Data) string {
+	if cluster == "" {
+		return msg.Namespace + "/" + msg.Name
+	}
+
+	return cluster + "/" + msg.Namespace + "/" + msg.Name
+}
+
+// customDetails returns the hpa details shown on the incident
+func customDetails(cluster string, msg *message.Data) map[string]interface{} {
+	details := map[string]interface{}{
+		"namespace":        msg.Namespace,
+		"name":             msg.Name,
+		"level":            msg.Level,
+		"alert":            msg.Alert,
+		"current_replicas": msg.CurrentReplicas,
+		"desired_replicas": msg.DesiredReplicas,
+		"max_replicas":     msg.MaxReplicas,
+	}
+	if cluster != "" {
+		details["cluster"] = cluster
+	}
+	if msg.TargetName != "" {
+		details["workload"] = msg.TargetKind + "/" + msg.TargetName
+	}
+	if msg.ConditionReason != "" {
+		details["condition_reason"] = msg.ConditionReason
+		details["condition_message"] = msg.ConditionMessage
+	}
+	if msg.Time != "" {
+		details["last_scale_time"] = msg.Time
+	}
+	if len(msg.Labels) > 0 {
+		details["labels"] = msg.Labels
+	}
+
+	return details
+}
@@ -0,0 +1,157 @@
+package pagerduty
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+func init() {
+	reporter.Register(Type, func(cfg config.Reporter, _ chan struct{}) (reporter.Reporter, error) {
+		r, err := CreateReporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+	reporter.RegisterValidator(Type, Validate)
+}
+
+const (
+	Type = "pagerduty"
+
+	ConfigRoutingKey = "routingKey"
+	ConfigURL        = "url"
+	ConfigCluster    = "cluster"
+	ConfigSource     = "source"
+	ConfigTimeout    = "timeout"
+
+	DefaultURL     = "https://events.pagerduty.com/v2/enqueue"
+	DefaultSource  = "hpa-reporter"
+	DefaultTimeout = 5 * time.Second
+)
+
+// Reporter sends trigger and resolve events to the pagerduty events api v2
+type Reporter struct {
+	name       string
+	url        string
+	routingKey string
+	cluster    string
+	source     string
+	client     *http.Client
+
+	// incidents is keyed by dedup key, it is kept across config reloads.
+	// it is only accessed by the single queue worker of the reporter.
+	incidents map[string]*incident
+}
+
+// incident is the open incident of an hpa
+type incident struct {
+	// firing is the level of every alert kind of the hpa that is not resolved yet
+	firing map[string]string
+}
+
+// Report sends the trigger event of a firing message. the incident is resolved when the last alert kind of the hpa is resolved,
+// the collector resolves every alert kind on its own.
+func (r *Reporter) Report(msg *message.Data) error {
+	key := dedupKey(r.cluster, msg)
+	inc, ok := r.incidents[key]
+
+	if msg.Level == message.LevelResolved {
+		if !ok {
+			// the other alert kinds of an unknown incident may still fire, it is not resolved blindly
+			logger.Debug("pagerduty incident is not known, skip resolve", zap.String("reporter", r.name), zap.String("dedup_key", key))
+			return nil
+		}
+		delete(inc.firing, msg.Alert)
+		if len(inc.firing) > 0 {
+			// another alert kind of the hpa still fires
+			return nil
+		}
+		if err := reporter.PostJSON(r.client, r.url, nil, &event{RoutingKey: r.routingKey, EventAction: ActionResolve, DedupKey: key}, nil); err != nil {
+			return err
+		}
+		// the incident is forgotten once the resolve is accepted, a retry still finds it
+		delete(r.incidents, key)
+
+		return nil
+	}
+
+	if !ok {
+		inc = &incident{firing: make(map[string]string)}
+		r.incidents[key] = inc
+	}
+	inc.firing[msg.Alert] = msg.Level
+
+	return reporter.PostJSON(r.client, r.url, nil, r.newTrigger(key, inc.severity(), msg), nil)
+}
+
+// severity returns the highest severity of the firing alert kinds
+func (i *incident) severity() string {
+	severity := SeverityInfo
+	for _, level := range i.firing {
+		switch {
+		case level == message.LevelCritical:
+			return SeverityCritical
+		case level == message.LevelWarning:
+			severity = SeverityWarning
+		}
+	}
+
+	return severity
+}
+
+// Validate checks the pagerduty reporter config
+func Validate(cfg config.Reporter) []config.Problem {
+	var problems []config.Problem
+
+	if cfg.Configs[ConfigRoutingKey] == "" {
+		problems = append(problems, config.Problem{Path: "configs." + ConfigRoutingKey, Message: fmt.Sprintf("%s is required", ConfigRoutingKey)})
+	}
+
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			problems = append(problems, config.Problem{Path: "configs." + ConfigTimeout, Message: fmt.Sprintf("invalid duration %q", v)})
+		}
+	}
+
+	return problems
+}
+
+// CreateReporter creates a new pagerduty reporter
+func CreateReporter(cfg config.Reporter) (*Reporter, error) {
+	if problems := Validate(cfg); len(problems) > 0 {
+		return nil, fmt.Errorf("pagerduty reporter(%s): %w", cfg.Name, &config.ValidationError{Problems: problems})
+	}
+
+	timeout := DefaultTimeout
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		timeout, _ = time.ParseDuration(v)
+	}
+
+	r := &Reporter{
+		name:       cfg.Name,
+		incidents:  reporter.State(cfg, func() map[string]*incident { return make(map[string]*incident) }),
+		url:        cfg.Configs[ConfigURL],
+		routingKey: cfg.Configs[ConfigRoutingKey],
+		cluster:    cfg.Configs[ConfigCluster],
+		source:     cfg.Configs[ConfigSource],
+		client:     &http.Client{Timeout: timeout},
+	}
+	if r.url == "" {
+		r.url = DefaultURL
+	}
+	if r.source == "" {
+		r.source = DefaultSource
+		if r.cluster != "" {
+			r.source = r.cluster
+		}
+	}
+
+	return r, nil
+}
@@ -0,0 +1,143 @@
+package pagerduty
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/reportertest"
+	"net/http"
+	"testing"
+)
+
+// events returns the events posted to the server
+func events(t *testing.T, srv *reportertest.Server) []event {
+	var result []event
+	for _, req := range srv.Requests() {
+		var e event
+		req.Decode(t, &e)
+		result = append(result, e)
+	}
+
+	return result
+}
+
+func TestReportLifecycle(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusAccepted, `{"status":"success","message":"Event processed"}`)
+	r, err := CreateReporter(config.Reporter{Name: "lifecycle", Configs: map[string]string{
+		ConfigRoutingKey: "R0UT1NGKEY",
+		ConfigURL:        srv.URL,
+		ConfigCluster:    "prod-1",
+	}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	hpa := func(alert, level string) *message.Data {
+		return &message.Data{Level: level, Alert: alert, Name: "my-hpa", Namespace: "test", TargetKind: "Deployment", TargetName: "my-app", MaxReplicas: 10}
+	}
+	steps := []*message.Data{
+		hpa(message.AlertReplicas, message.LevelCritical),
+		hpa(message.AlertScalingLimited, message.LevelWarning),
+		// Replicas still fires, the incident stays open
+		hpa(message.AlertScalingLimited, message.LevelResolved),
+		hpa(message.AlertReplicas, message.LevelResolved),
+	}
+	for _, msg := range steps {
+		if err = r.Report(msg); err != nil {
+			t.Fatalf("failed to report %s %s: %v", msg.Alert, msg.Level, err)
+		}
+	}
+
+	got := events(t, srv)
+	if len(got) != 3 {
+		t.Fatalf("expected 2 triggers and 1 resolve, got %+v", got)
+	}
+	for _, e := range got {
+		if e.RoutingKey != "R0UT1NGKEY" || e.DedupKey != "prod-1/test/my-hpa" {
+			t.Errorf("unexpected event: %+v", e)
+		}
+	}
+	if got[0].EventAction != ActionTrigger || got[0].Payload.Source != "prod-1" || got[0].Payload.Class != message.AlertReplicas {
+		t.Errorf("unexpected trigger: %+v", got[0].Payload)
+	}
+	if d := got[0].Payload.CustomDetails; d["workload"] != "Deployment/my-app" || d["max_replicas"] != float64(10) || d["cluster"] != "prod-1" {
+		t.Errorf("unexpected custom details: %v", d)
+	}
+	// the warning kind does not lower the severity of the critical incident
+	if got[1].EventAction != ActionTrigger || got[1].Payload.Class != message.AlertScalingLimited || got[1].Payload.Severity != SeverityCritical {
+		t.Errorf("unexpected second trigger: %+v", got[1].Payload)
+	}
+	if got[2].EventAction != ActionResolve || got[2].Payload != nil {
+		t.Errorf("expected resolve after the last alert kind, got %+v", got[2])
+	}
+}
+
+func TestReportResolveUnknown(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusAccepted, "")
+	r, err := CreateReporter(config.Reporter{Name: "unknown", Configs: map[string]string{ConfigRoutingKey: "key", ConfigURL: srv.URL}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	// the other alert kinds of an incident that is not known may still fire
+	if err = r.Report(&message.Data{Level: message.LevelResolved, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if got := events(t, srv); len(got) != 0 {
+		t.Errorf("expected no resolve event, got %+v", got)
+	}
+	if r.url == DefaultURL {
+		t.Error("expected the url to be overridden")
+	}
+}
+
+func TestReportReload(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusAccepted, "")
+	cfg := config.Reporter{Name: "reload", Type: Type, Configs: map[string]string{ConfigRoutingKey: "key", ConfigURL: srv.URL}}
+	r, err := CreateReporter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	hpa := func(alert, level string) *message.Data {
+		return &message.Data{Level: level, Alert: alert, Name: "my-hpa", Namespace: "test"}
+	}
+	for _, msg := range []*message.Data{hpa(message.AlertReplicas, message.LevelCritical), hpa(message.AlertScalingLimited, message.LevelWarning)} {
+		if err = r.Report(msg); err != nil {
+			t.Fatalf("failed to report: %v", err)
+		}
+	}
+
+	// the reporter created on reload knows that Replicas still fires
+	cfg.Configs[ConfigTimeout] = "10s"
+	reloaded, err := CreateReporter(cfg)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	if err = reloaded.Report(hpa(message.AlertScalingLimited, message.LevelResolved)); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if got := events(t, srv); len(got) != 2 {
+		t.Fatalf("expected the incident to stay open, got %+v", got)
+	}
+
+	// a failed resolve is retried
+	srv.SetStatus(http.StatusServiceUnavailable)
+	if err = reloaded.Report(hpa(message.AlertReplicas, message.LevelResolved)); err == nil {
+		t.Fatal("expected error from unavailable api")
+	}
+	srv.SetStatus(http.StatusAccepted)
+	if err = reloaded.Report(hpa(message.AlertReplicas, message.LevelResolved)); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if got := events(t, srv); len(got) != 4 || got[3].EventAction != ActionResolve {
+		t.Errorf("expected the incident to be resolved after the last alert kind, got %+v", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	reportertest.CheckValidate(t, Type, Validate, []reportertest.ValidateCase{
+		{Configs: map[string]string{ConfigRoutingKey: "key"}},
+		{Configs: map[string]string{}, Want: []string{"configs.routingKey"}},
+		{Configs: map[string]string{ConfigRoutingKey: "key", ConfigTimeout: "soon"}, Want: []string{"configs.timeout"}},
+	})
+}
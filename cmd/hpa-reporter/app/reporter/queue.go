@@ -0,0 +1,202 @@
+package reporter
+
+import (
+	"expvar"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MetricDepth    = "depth"
+	MetricCapacity = "capacity"
+	MetricEnqueued = "enqueued"
+	MetricDropped  = "dropped"
+	MetricSent     = "sent"
+	MetricFailed   = "failed"
+)
+
+// queueMetrics is published at /debug/vars as reporter_queue.<reporter name>.<metric>.
+// the metrics of a reporter name are kept across config reloads.
+var queueMetrics = expvar.NewMap("reporter_queue")
+
+// metricsFor returns the metrics of the reporter
+func metricsFor(name string) *expvar.Map {
+	if m, ok := queueMetrics.Get(name).(*expvar.Map); ok {
+		return m
+	}
+
+	m := new(expvar.Map).Init()
+	queueMetrics.Set(name, m)
+	return m
+}
+
+// item is a queued message, spoolID is set if it is replayed from the spool
+type item struct {
+	msg     *message.Data
+	spoolID string
+}
+
+// queue is the bounded buffer in front of a reporter. one worker drains it, so a reporter
+// gets its messages in order and never more than one at a time.
+type queue struct {
+	name         string
+	msgChan      chan item
+	overflow     string
+	blockTimeout time.Duration
+	shutdown     chan struct{}
+
+	// drain makes the worker deliver the rest of the queue and stop, stopped is closed when it stopped
+	drain   chan struct{}
+	stopped chan struct{}
+	sent    atomic.Int64
+	failed  atomic.Int64
+
+	// done gets the delivery result of every item, dropped every item that is dropped
+	done    func(name string, it item, err error)
+	dropped func(it item)
+
+	metrics *expvar.Map
+	depth   *expvar.Int
+}
+
+// newQueue creates the queue of a reporter
+func newQueue(name string, cfg config.QueueConfig, shutdown chan struct{}) *queue {
+	cfg = cfg.WithDefaults()
+	q := &queue{
+		name:         name,
+		msgChan:      make(chan item, cfg.Size),
+		overflow:     cfg.Overflow,
+		blockTimeout: cfg.BlockTimeout,
+		shutdown:     shutdown,
+		drain:        make(chan struct{}),
+		stopped:      make(chan struct{}),
+		metrics:      metricsFor(name),
+		depth:        new(expvar.Int),
+	}
+
+	capacity := new(expvar.Int)
+	capacity.Set(int64(cfg.Size))
+	q.metrics.Set(MetricCapacity, capacity)
+	q.metrics.Set(MetricDepth, q.depth)
+
+	return q
+}
+
+// push adds the item to the queue and applies the overflow policy if it is full
+func (q *queue) push(it item) {
+	defer q.updateDepth()
+
+	select {
+	case q.msgChan <- it:
+		q.metrics.Add(MetricEnqueued, 1)
+		return
+	default:
+	}
+
+	switch q.overflow {
+	case config.OverflowDropNewest:
+		q.drop(it, "queue is full")
+	case config.OverflowBlock:
+		timer := time.NewTimer(q.blockTimeout)
+		defer timer.Stop()
+
+		select {
+		case q.msgChan <- it:
+			q.metrics.Add(MetricEnqueued, 1)
+		case <-timer.C:
+			q.drop(it, "queue is full after "+q.blockTimeout.String())
+		case <-q.shutdown:
+			q.drop(it, "reporter is stopped")
+		}
+	default:
+		// drop the oldest message until the new one fits, the worker may take one at the same time
+		for {
+			select {
+			case q.msgChan <- it:
+				q.metrics.Add(MetricEnqueued, 1)
+				return
+			default:
+			}
+
+			select {
+			case oldest := <-q.msgChan:
+				q.drop(oldest, "queue is full, dropped the oldest message")
+			default:
+			}
+		}
+	}
+}
+
+// drop counts and logs a message that is not delivered
+func (q *queue) drop(it item, reason string) {
+	q.metrics.Add(MetricDropped, 1)
+	logger.Warn("[reporter] message dropped", append(messageFields(it.msg), zap.String("reporter", q.name), zap.String("reason", reason))...)
+	if q.dropped != nil {
+		q.dropped(it)
+	}
+}
+
+// updateDepth publishes the number of waiting messages
+func (q *queue) updateDepth() {
+	q.depth.Set(int64(len(q.msgChan)))
+}
+
+// run delivers the queued messages to the reporter until the queue is shut down or drained
+func (q *queue) run(rep Reporter) {
+	defer close(q.stopped)
+
+	for {
+		select {
+		case it := <-q.msgChan:
+			q.deliver(rep, it)
+		case <-q.drain:
+			for {
+				select {
+				case it := <-q.msgChan:
+					q.deliver(rep, it)
+				case <-q.shutdown:
+					return
+				default:
+					return
+				}
+			}
+		case <-q.shutdown:
+			return
+		}
+	}
+}
+
+// deliver reports the item and counts the result
+func (q *queue) deliver(rep Reporter, it item) {
+	q.updateDepth()
+	err := rep.Report(it.msg)
+	if err != nil {
+		q.failed.Add(1)
+		q.metrics.Add(MetricFailed, 1)
+		logger.Error("[reporter] failed to report message", append(messageFields(it.msg), zap.String("reporter", q.name), zap.Error(err))...)
+	} else {
+		q.sent.Add(1)
+		q.metrics.Add(MetricSent, 1)
+	}
+	if q.done != nil {
+		q.done(q.name, it, err)
+	}
+}
+
+// abandon takes the items that are still queued, the worker has to be stopped
+func (q *queue) abandon() []item {
+	var items []item
+	for {
+		select {
+		case it := <-q.msgChan:
+			items = append(items, it)
+		default:
+			q.updateDepth()
+			return items
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package reporter
+
+import (
+	"expvar"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"testing"
+	"time"
+)
+
+func TestQueueOverflow(t *testing.T) {
+	tests := []struct {
+		overflow string
+		want     []string
+	}{
+		{config.OverflowDropOldest, []string{"c", "d"}},
+		{config.OverflowDropNewest, []string{"a", "b"}},
+		{config.OverflowBlock, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		shutdown := make(chan struct{})
+		q := newQueue("test-"+tt.overflow, config.QueueConfig{Size: 2, Overflow: tt.overflow, BlockTimeout: 10 * time.Millisecond}, shutdown)
+		dropped := metricValue(q, MetricDropped)
+		for _, name := range []string{"a", "b", "c", "d"} {
+			q.push(item{msg: &message.Data{Name: name}})
+		}
+
+		if got := metricValue(q, MetricDropped) - dropped; got != 2 {
+			t.Errorf("%s: dropped = %d, want 2", tt.overflow, got)
+		}
+		if got := q.metrics.Get(MetricDepth).String(); got != "2" {
+			t.Errorf("%s: depth = %s, want 2", tt.overflow, got)
+		}
+		for _, want := range tt.want {
+			if it := <-q.msgChan; it.msg.Name != want {
+				t.Errorf("%s: got %s, want %s", tt.overflow, it.msg.Name, want)
+			}
+		}
+		close(shutdown)
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	q := newQueue("test-block-wait", config.QueueConfig{Size: 1, Overflow: config.OverflowBlock, BlockTimeout: time.Second}, shutdown)
+	dropped := metricValue(q, MetricDropped)
+	q.push(item{msg: &message.Data{Name: "a"}})
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		<-q.msgChan
+	}()
+	q.push(item{msg: &message.Data{Name: "b"}})
+
+	if got := metricValue(q, MetricDropped) - dropped; got != 0 {
+		t.Errorf("expected no drop while the worker frees a slot, got %d", got)
+	}
+	if it := <-q.msgChan; it.msg.Name != "b" {
+		t.Errorf("got %s, want b", it.msg.Name)
+	}
+}
+
+func TestQueueRun(t *testing.T) {
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	rep := &fakeReporter{received: make(chan *message.Data, 2)}
+	q := newQueue("test-run", config.QueueConfig{}, shutdown)
+	go q.run(rep)
+
+	q.push(item{msg: &message.Data{Name: "a"}})
+	q.push(item{msg: &message.Data{Name: "b"}})
+
+	for _, want := range []string{"a", "b"} {
+		select {
+		case msg := <-rep.received:
+			if msg.Name != want {
+				t.Errorf("got %s, want %s", msg.Name, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s to be reported", want)
+		}
+	}
+}
+
+// metricValue returns the counter of the queue, metrics are kept across tests with the same reporter name
+func metricValue(q *queue, key string) int64 {
+	if v, ok := q.metrics.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
@@ -0,0 +1,123 @@
+package reporter
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a reporter from its config
+type Factory func(cfg config.Reporter, shutdown chan struct{}) (Reporter, error)
+
+// Validator checks the reporter config without creating the reporter.
+// problem paths are relative to the reporter entry, such as "configs.url".
+type Validator func(cfg config.Reporter) []config.Problem
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+	validators  = make(map[string]Validator)
+)
+
+// Register makes a reporter factory available by the provided type name.
+// It is meant to be called from the init function of each reporter package.
+func Register(typ string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("reporter: Register factory is nil")
+	}
+	if _, dup := factories[typ]; dup {
+		panic(fmt.Sprintf("reporter: Register called twice for type %q", typ))
+	}
+	factories[typ] = factory
+}
+
+// RegisterValidator adds a config validator for the reporter type.
+// it is optional, reporter types without a validator are only checked for being registered.
+func RegisterValidator(typ string, validator Validator) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if validator == nil {
+		panic("reporter: RegisterValidator validator is nil")
+	}
+	if _, dup := validators[typ]; dup {
+		panic(fmt.Sprintf("reporter: RegisterValidator called twice for type %q", typ))
+	}
+	validators[typ] = validator
+}
+
+// Validate checks the type and the configs of every reporter and returns the problems with their yaml path
+func Validate(reporters []config.Reporter) []config.Problem {
+	var problems []config.Problem
+	for i, cfg := range reporters {
+		p := fmt.Sprintf("reporters[%d]", i)
+		if cfg.Type == "" {
+			// reported by config.Validate
+			continue
+		}
+		if _, ok := lookup(cfg.Type); !ok {
+			problems = append(problems, config.Problem{
+				Path:    p + ".type",
+				Message: fmt.Sprintf("unknown reporter type %q (available: %s)", cfg.Type, strings.Join(Types(), ", ")),
+			})
+			continue
+		}
+
+		factoriesMu.RLock()
+		validator := validators[cfg.Type]
+		factoriesMu.RUnlock()
+		if validator == nil {
+			continue
+		}
+		// referenced values are only known after they are resolved, so their problems are not reported
+		runtime := make(map[string]bool)
+		configs := make(map[string]string, len(cfg.Configs)+len(cfg.SecretRefs))
+		for key, value := range cfg.Configs {
+			configs[key] = value
+			runtime["configs."+key] = config.HasReference(value)
+		}
+		for key := range cfg.SecretRefs {
+			configs[key] = "secretRef"
+			runtime["configs."+key] = true
+		}
+		cfg.Configs = configs
+
+		for _, problem := range validator(cfg) {
+			if runtime[problem.Path] {
+				continue
+			}
+			problem.Path = p + "." + problem.Path
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems
+}
+
+// Types returns a sorted list of the registered reporter types
+func Types() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	types := make([]string, 0, len(factories))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// lookup returns the factory registered for the type
+func lookup(typ string) (Factory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	factory, ok := factories[typ]
+	return factory, ok
+}
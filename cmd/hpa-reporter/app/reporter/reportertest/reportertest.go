@@ -0,0 +1,108 @@
+// Package reportertest has the helpers shared by the tests of the reporter types
+package reportertest
+
+import (
+	"encoding/json"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// ValidateCase is a reporter config and the problem paths its validator returns
+type ValidateCase struct {
+	Configs map[string]string
+	Want    []string
+}
+
+// CheckValidate runs the validator on every case and compares the problem paths
+func CheckValidate(t *testing.T, typ string, validate reporter.Validator, cases []ValidateCase) {
+	t.Helper()
+
+	for _, tt := range cases {
+		problems := validate(config.Reporter{Name: "test", Type: typ, Configs: tt.Configs})
+		if len(problems) != len(tt.Want) {
+			t.Errorf("Validate(%v) = %v, want %v", tt.Configs, problems, tt.Want)
+			continue
+		}
+		for i, p := range problems {
+			if p.Path != tt.Want[i] {
+				t.Errorf("Validate(%v) problem path %q, want %q", tt.Configs, p.Path, tt.Want[i])
+			}
+		}
+	}
+}
+
+// Request is a request received by the Server
+type Request struct {
+	Method string
+	// Path is the escaped path with the query
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// Decode unmarshals the json body of the request
+func (r Request) Decode(t *testing.T, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("failed to decode request %s: %v", r.Path, err)
+	}
+}
+
+// Server is a httptest server that records the requests and answers with Status and Response
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	status   int
+	response string
+}
+
+// NewServer starts a server that answers every request with the status and the response body.
+// it is closed when the test ends.
+func NewServer(t *testing.T, status int, response string) *Server {
+	s := &Server{status: status, response: response}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// serve records the request and writes the configured response
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := r.URL.EscapedPath()
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{Method: r.Method, Path: path, Header: r.Header.Clone(), Body: body})
+	status, response := s.status, s.response
+	s.mu.Unlock()
+
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, response)
+}
+
+// SetStatus changes the status of the next responses
+func (s *Server) SetStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status = status
+}
+
+// Requests returns the received requests in order
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request(nil), s.requests...)
+}
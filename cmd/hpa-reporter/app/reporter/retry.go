@@ -0,0 +1,179 @@
+package reporter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/logger"
+	"go.uber.org/zap"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// ErrStopped is returned when the reporter is stopped while it waits for the next attempt
+var ErrStopped = errors.New("reporter is stopped")
+
+// HTTPError is a non-2xx response of a reporter endpoint
+type HTTPError struct {
+	StatusCode int
+	RetryAfter time.Duration
+	Body       string
+}
+
+// NewHTTPError reads the status, the Retry-After header and the start of the body of the response
+func NewHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Body:       string(bytes.TrimSpace(body)),
+	}
+}
+
+// Error implements error
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+// parseRetryAfter reads the Retry-After header in seconds or as an http date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// retryable reports whether the delivery may succeed if it is sent again, and how long the endpoint asked to wait
+func retryable(err error) (bool, time.Duration) {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= 500 {
+			return true, httpErr.RetryAfter
+		}
+		return false, 0
+	}
+
+	return temporary(err), 0
+}
+
+// temporary reports whether the request failed on the connection, such as a timeout, a refused or reset connection.
+// every client.Do error is a *url.Error that implements net.Error, so permanent errors such as an unsupported
+// scheme are told apart by the wrapped error.
+func temporary(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryReporter sends the message again on network errors, 5xx and 429 with capped exponential backoff
+type retryReporter struct {
+	name     string
+	reporter Reporter
+	config   config.RetryConfig
+	shutdown chan struct{}
+
+	jitter func(d time.Duration) time.Duration
+}
+
+// newRetryReporter wraps the reporter with the retry config of the reporter
+func newRetryReporter(name string, rep Reporter, cfg config.RetryConfig, shutdown chan struct{}) *retryReporter {
+	return &retryReporter{
+		name:     name,
+		reporter: rep,
+		config:   cfg.WithDefaults(),
+		shutdown: shutdown,
+		jitter:   equalJitter,
+	}
+}
+
+// Report delivers the message and retries until it succeeds, fails permanently or the attempts are used up
+func (r *retryReporter) Report(msg *message.Data) error {
+	for attempt := 1; ; attempt++ {
+		err := r.reporter.Report(msg)
+		if err == nil {
+			return nil
+		}
+
+		retry, retryAfter := retryable(err)
+		if !retry || attempt >= r.config.MaxAttempts {
+			if attempt > 1 {
+				return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+
+		wait := r.backoff(attempt)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		logger.Warn("[reporter] delivery failed, retrying", append(messageFields(msg), zap.String("reporter", r.name),
+			zap.Int("attempt", attempt), zap.Duration("backoff", wait), zap.Error(err))...)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-r.shutdown:
+			timer.Stop()
+			return fmt.Errorf("%w after %d attempts: %w", ErrStopped, attempt, err)
+		}
+	}
+}
+
+// backoff returns the wait before the next attempt, doubled for every attempt up to the max backoff
+func (r *retryReporter) backoff(attempt int) time.Duration {
+	d := r.config.InitialBackoff
+	for i := 1; i < attempt && d < r.config.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.config.MaxBackoff {
+		d = r.config.MaxBackoff
+	}
+
+	return r.jitter(d)
+}
+
+// equalJitter returns a random duration between d/2 and d, so retries of many reporters do not line up
+func equalJitter(d time.Duration) time.Duration {
+	half := d / 2
+	if half <= 0 {
+		return d
+	}
+
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// messageFields returns the log fields that identify the message
+func messageFields(msg *message.Data) []zap.Field {
+	return []zap.Field{
+		zap.String("namespace", msg.Namespace),
+		zap.String("name", msg.Name),
+		zap.String("alert", msg.Alert),
+		zap.String("severity", msg.Level),
+		zap.String("time", msg.Time),
+	}
+}
@@ -0,0 +1,153 @@
+package reporter
+
+import (
+	"errors"
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// scriptedReporter returns the errors in order and nil after them
+type scriptedReporter struct {
+	errs     []error
+	attempts int
+}
+
+func (s *scriptedReporter) Report(_ *message.Data) error {
+	s.attempts++
+	if len(s.errs) == 0 {
+		return nil
+	}
+	err := s.errs[0]
+	s.errs = s.errs[1:]
+	return err
+}
+
+func TestRetryReporter(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	tests := []struct {
+		name     string
+		errs     []error
+		wantErr  bool
+		attempts int
+	}{
+		{"success", nil, false, 1},
+		{"5xx then success", []error{&HTTPError{StatusCode: 502}}, false, 2},
+		{"429 then network then success", []error{&HTTPError{StatusCode: 429}, netErr}, false, 3},
+		{"4xx is not retried", []error{&HTTPError{StatusCode: 400}}, true, 1},
+		{"plain error is not retried", []error{errors.New("bad payload")}, true, 1},
+		{"attempts used up", []error{netErr, netErr, netErr, netErr}, true, 3},
+	}
+
+	for _, tt := range tests {
+		rep := &scriptedReporter{errs: tt.errs}
+		r := newRetryReporter("test", rep, config.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}, make(chan struct{}))
+
+		err := r.Report(&message.Data{Name: "my-hpa", Namespace: "test"})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if rep.attempts != tt.attempts {
+			t.Errorf("%s: attempts = %d, want %d", tt.name, rep.attempts, tt.attempts)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	do := func(url string) error {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// a closed listener refuses the connection
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	refused := "http://" + l.Addr().String()
+	_ = l.Close()
+
+	// a server that never answers times out
+	hang := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { <-r.Context().Done() }))
+	defer hang.Close()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"invalid scheme", do("ftp://example.com/hook"), false},
+		{"missing host", do("http:///hook"), false},
+		{"connection refused", do(refused), true},
+		{"timeout", do(hang.URL), true},
+		{"connection reset", fmt.Errorf("post: %w", syscall.ECONNRESET), true},
+		{"5xx", &HTTPError{StatusCode: 503}, true},
+		{"4xx", &HTTPError{StatusCode: 404}, false},
+	}
+
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Fatalf("%s: expected an error", tt.name)
+		}
+		if got, _ := retryable(tt.err); got != tt.want {
+			t.Errorf("%s: retryable(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryReporterBackoff(t *testing.T) {
+	r := newRetryReporter("test", &scriptedReporter{}, config.RetryConfig{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}, make(chan struct{}))
+	r.jitter = func(d time.Duration) time.Duration { return d }
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := r.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := equalJitter(time.Second); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("equalJitter(1s) = %s, want between 500ms and 1s", d)
+		}
+	}
+}
+
+func TestRetryReporterStopped(t *testing.T) {
+	shutdown := make(chan struct{})
+	rep := &scriptedReporter{errs: []error{&HTTPError{StatusCode: 503, RetryAfter: time.Hour}}}
+	r := newRetryReporter("test", rep, config.RetryConfig{}, shutdown)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(shutdown)
+	}()
+
+	if err := r.Report(&message.Data{}); !errors.Is(err, ErrStopped) {
+		t.Errorf("expected ErrStopped while waiting for Retry-After, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("parseRetryAfter(3) = %s", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); got < 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(date) = %s", got)
+	}
+	for _, v := range []string{"", "soon", "-1"} {
+		if got := parseRetryAfter(v); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %s, want 0", v, got)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package reporter
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"k8s.io/apimachinery/pkg/labels"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// route is a compiled config.Route
+type route struct {
+	namespace   string
+	name        string
+	nameRegex   *regexp.Regexp
+	selector    labels.Selector
+	annotations map[string]string
+	levels      []string
+
+	receivers []string
+	cont      bool
+	routes    []*route
+}
+
+// newRoute compiles the route tree. children without receivers get the receivers of their parent.
+func newRoute(cfg *config.Route, parent []string) (*route, error) {
+	r := &route{
+		namespace:   cfg.Match.Namespace,
+		name:        cfg.Match.Name,
+		annotations: cfg.Match.Annotations,
+		levels:      cfg.Match.Levels,
+		receivers:   cfg.Receivers,
+		cont:        cfg.Continue,
+	}
+	if len(r.receivers) == 0 {
+		r.receivers = parent
+	}
+
+	if _, err := path.Match(r.namespace, ""); err != nil {
+		return nil, fmt.Errorf("invalid namespace glob %q: %w", r.namespace, err)
+	}
+	if cfg.Match.NameRegex != "" {
+		re, err := regexp.Compile(cfg.Match.NameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nameRegex: %w", err)
+		}
+		r.nameRegex = re
+	}
+	if strings.TrimSpace(cfg.Match.Selector) != "" {
+		selector, err := labels.Parse(cfg.Match.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector: %w", err)
+		}
+		r.selector = selector
+	}
+
+	for i := range cfg.Routes {
+		child, err := newRoute(&cfg.Routes[i], r.receivers)
+		if err != nil {
+			return nil, fmt.Errorf("routes[%d]: %w", i, err)
+		}
+		r.routes = append(r.routes, child)
+	}
+
+	return r, nil
+}
+
+// receiversOf returns the receivers of the message, or nil if the route does not match it.
+// like alertmanager, the first matching child decides unless it has continue set.
+func (r *route) receiversOf(msg *message.Data) []string {
+	if !r.matches(msg) {
+		return nil
+	}
+
+	var receivers []string
+	var matched bool
+	for _, child := range r.routes {
+		if !child.matches(msg) {
+			continue
+		}
+		matched = true
+		receivers = appendUnique(receivers, child.receiversOf(msg)...)
+		if !child.cont {
+			break
+		}
+	}
+	if !matched {
+		return r.receivers
+	}
+
+	return receivers
+}
+
+// matches reports whether the message matches every matcher of the route
+func (r *route) matches(msg *message.Data) bool {
+	if r.namespace != "" {
+		if ok, _ := path.Match(r.namespace, msg.Namespace); !ok {
+			return false
+		}
+	}
+	if r.name != "" && r.name != msg.Name {
+		return false
+	}
+	if r.nameRegex != nil && !r.nameRegex.MatchString(msg.Name) {
+		return false
+	}
+	if r.selector != nil && !r.selector.Matches(labels.Set(msg.Labels)) {
+		return false
+	}
+	for k, v := range r.annotations {
+		if msg.Annotations[k] != v {
+			return false
+		}
+	}
+	if len(r.levels) > 0 && !contains(r.levels, msg.Level) && (msg.FiringLevel == "" || !contains(r.levels, msg.FiringLevel)) {
+		return false
+	}
+
+	return true
+}
+
+// appendUnique appends the values that are not in list yet
+func appendUnique(list []string, values ...string) []string {
+	for _, v := range values {
+		if !contains(list, v) {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
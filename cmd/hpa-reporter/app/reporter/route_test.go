@@ -0,0 +1,123 @@
+package reporter
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testRoute = `
+receivers: [default]
+routes:
+  - match:
+      namespace: payments
+    receivers: [payments-slack]
+    continue: true
+  - match:
+      namespace: payments
+      levels: [critical]
+    receivers: [oncall]
+  - match:
+      namespace: team-*
+      selector: tier=web
+    routes:
+      - match:
+          annotations:
+            owner: search
+        receivers: [search]
+  - match:
+      nameRegex: ^batch-
+    receivers: []
+`
+
+func TestRoute(t *testing.T) {
+	var cfg config.Route
+	if err := yaml.Unmarshal([]byte(testRoute), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	r, err := newRoute(&cfg, nil)
+	if err != nil {
+		t.Fatalf("failed to compile route: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		msg  message.Data
+		want []string
+	}{
+		{"default", message.Data{Namespace: "shop", Name: "web", Level: message.LevelWarning}, []string{"default"}},
+		{"payments warning", message.Data{Namespace: "payments", Name: "api", Level: message.LevelWarning}, []string{"payments-slack"}},
+		{"payments critical", message.Data{Namespace: "payments", Name: "api", Level: message.LevelCritical}, []string{"payments-slack", "oncall"}},
+		{"payments critical resolved", message.Data{Namespace: "payments", Name: "api", Level: message.LevelResolved, FiringLevel: message.LevelCritical}, []string{"payments-slack", "oncall"}},
+		{"payments warning resolved", message.Data{Namespace: "payments", Name: "api", Level: message.LevelResolved, FiringLevel: message.LevelWarning}, []string{"payments-slack"}},
+		{"child route", message.Data{Namespace: "team-a", Labels: map[string]string{"tier": "web"}, Annotations: map[string]string{"owner": "search"}}, []string{"search"}},
+		{"no child matched", message.Data{Namespace: "team-a", Labels: map[string]string{"tier": "web"}}, []string{"default"}},
+		{"selector not matched", message.Data{Namespace: "team-a", Labels: map[string]string{"tier": "db"}}, []string{"default"}},
+		{"inherited receivers", message.Data{Namespace: "shop", Name: "batch-1"}, []string{"default"}},
+	}
+
+	for _, tt := range tests {
+		if got := r.receiversOf(&tt.msg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: receivers = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReportRoute(t *testing.T) {
+	h, err := NewReporterHandler([]config.Reporter{
+		{Name: "a", Type: "fake"},
+		{Name: "b", Type: "fake"},
+		{Name: "oncall", Type: "fake"},
+	}, &config.Route{
+		Receivers: []string{"a"},
+		Routes: []config.Route{
+			{Match: config.RouteMatch{Namespace: "payments"}, Receivers: []string{"b"}, Continue: true},
+			{Match: config.RouteMatch{Namespace: "payments", Levels: []string{message.LevelCritical}}, Receivers: []string{"oncall"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+
+	a := h.set.Load().entries[0].reporter.(*fakeReporter)
+	b := h.set.Load().entries[1].reporter.(*fakeReporter)
+	oncall := h.set.Load().entries[2].reporter.(*fakeReporter)
+
+	h.Report(&message.Data{Name: "api", Namespace: "payments", Level: message.LevelWarning})
+	expectReceived(t, b, "api")
+
+	// receivers set by annotations or policies narrow the receivers of the route
+	h.Report(&message.Data{Name: "web", Namespace: "payments", Level: message.LevelCritical, Receivers: []string{"a", "oncall"}})
+	expectReceived(t, oncall, "web")
+
+	// the level matcher of the route still applies to them
+	h.Report(&message.Data{Name: "worker", Namespace: "payments", Level: message.LevelWarning, Receivers: []string{"oncall"}})
+
+	select {
+	case msg := <-a.received:
+		t.Errorf("unexpected message %+v", msg)
+	case msg := <-b.received:
+		t.Errorf("unexpected message %+v", msg)
+	case msg := <-oncall.received:
+		t.Errorf("unexpected message %+v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// expectReceived waits for the message of the hpa name
+func expectReceived(t *testing.T, f *fakeReporter, name string) {
+	t.Helper()
+
+	select {
+	case msg := <-f.received:
+		if msg.Name != name {
+			t.Errorf("got %s, want %s", msg.Name, name)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected %s to be reported", name)
+	}
+}
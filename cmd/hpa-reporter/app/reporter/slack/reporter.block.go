@@ -0,0 +1,111 @@
+package slack
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"strings"
+	"time"
+)
+
+const (
+	ColorWarning  = "#f2c744"
+	ColorCritical = "#e01e5a"
+	ColorResolved = "#2eb67d"
+	ColorDefault  = "#439fe0"
+
+	barWidth = 10
+)
+
+type (
+	// payload is slack incoming webhook message
+	payload struct {
+		Text        string       `json:"text"`
+		Attachments []attachment `json:"attachments"`
+	}
+
+	// attachment is used to colour the block kit message
+	attachment struct {
+		Color  string  `json:"color"`
+		Blocks []block `json:"blocks"`
+	}
+
+	// block is slack block kit layout block
+	block struct {
+		Type     string `json:"type"`
+		Text     *text  `json:"text,omitempty"`
+		Fields   []text `json:"fields,omitempty"`
+		Elements []text `json:"elements,omitempty"`
+	}
+
+	// text is slack block kit text object
+	text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+)
+
+// newPayload builds block kit message from message data
+func newPayload(msg *message.Data) *payload {
+	title := fmt.Sprintf("HPA %s: %s/%s", strings.ToUpper(msg.Level), msg.Namespace, msg.Name)
+
+	blocks := []block{
+		{Type: "header", Text: &text{Type: "plain_text", Text: title}},
+		{Type: "section", Text: &text{Type: "mrkdwn", Text: "*Alert*\n" + msg.Summary()}},
+		{Type: "section", Fields: []text{
+			{Type: "mrkdwn", Text: "*Namespace*\n" + msg.Namespace},
+			{Type: "mrkdwn", Text: "*Name*\n" + msg.Name},
+		}},
+		{Type: "section", Text: &text{Type: "mrkdwn", Text: fmt.Sprintf("*Replicas*\n`%s` %d/%d",
+			replicaBar(msg.CurrentReplicas, msg.MaxReplicas), msg.CurrentReplicas, msg.MaxReplicas)}},
+		{Type: "section", Fields: []text{
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Current*\n%d", msg.CurrentReplicas)},
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Desired*\n%d", msg.DesiredReplicas)},
+			{Type: "mrkdwn", Text: fmt.Sprintf("*Max*\n%d", msg.MaxReplicas)},
+		}},
+	}
+	if msg.ConditionReason != "" {
+		blocks = append(blocks, block{Type: "section", Text: &text{Type: "mrkdwn",
+			Text: fmt.Sprintf("*Condition*\n`%s` %s", msg.ConditionReason, msg.ConditionMessage)}})
+	}
+	if msg.Level == message.LevelResolved {
+		blocks = append(blocks, block{Type: "section", Text: &text{Type: "mrkdwn",
+			Text: fmt.Sprintf("*Resolved after*\n%s", msg.Duration.Round(time.Second))}})
+	}
+	if msg.Time != "" {
+		blocks = append(blocks, block{Type: "context", Elements: []text{
+			{Type: "mrkdwn", Text: "last scale time: " + msg.Time},
+		}})
+	}
+
+	return &payload{
+		Text:        title + ": " + msg.Summary(),
+		Attachments: []attachment{{Color: levelColor(msg.Level), Blocks: blocks}},
+	}
+}
+
+// levelColor returns attachment colour for message level
+func levelColor(level string) string {
+	switch level {
+	case message.LevelWarning:
+		return ColorWarning
+	case message.LevelCritical:
+		return ColorCritical
+	case message.LevelResolved:
+		return ColorResolved
+	default:
+		return ColorDefault
+	}
+}
+
+// replicaBar draws current/max replicas as a fixed width bar
+func replicaBar(current, max int32) string {
+	filled := barWidth
+	if max > 0 && current < max {
+		filled = int(current) * barWidth / int(max)
+	}
+	if filled < 0 {
+		filled = 0
+	}
+
+	return strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
+}
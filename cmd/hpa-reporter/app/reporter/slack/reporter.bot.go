@@ -0,0 +1,130 @@
+package slack
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"net/http"
+)
+
+const (
+	methodPostMessage = "chat.postMessage"
+	methodUpdate      = "chat.update"
+)
+
+type (
+	// botMessage is the request body of chat.postMessage and chat.update
+	botMessage struct {
+		Channel  string `json:"channel"`
+		TS       string `json:"ts,omitempty"`
+		ThreadTS string `json:"thread_ts,omitempty"`
+		payload
+	}
+
+	// botResponse is the response body of the slack web api
+	botResponse struct {
+		OK      bool   `json:"ok"`
+		Error   string `json:"error"`
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+
+	// thread is the parent message of an hpa alert thread
+	thread struct {
+		channel string
+		ts      string
+
+		// firing is the set of alert kinds of the hpa that are not resolved yet
+		firing map[string]struct{}
+	}
+)
+
+// bot sends messages with the slack web api using a bot token.
+// alerts for the same hpa are grouped into one thread.
+type bot struct {
+	apiURL  string
+	token   string
+	channel string
+	client  *http.Client
+
+	// threads is keyed by namespace/name, it is kept across config reloads.
+	// it is only accessed by the single queue worker of the reporter.
+	threads map[string]*thread
+}
+
+// newBot creates a new bot api sender that posts into the threads
+func newBot(apiURL, token, channel string, client *http.Client, threads map[string]*thread) *bot {
+	return &bot{
+		apiURL:  apiURL,
+		token:   token,
+		channel: channel,
+		client:  client,
+		threads: threads,
+	}
+}
+
+// send posts the message into the thread of the hpa
+func (b *bot) send(msg *message.Data) error {
+	k := msg.Namespace + "/" + msg.Name
+	parent, ok := b.threads[k]
+
+	if msg.Level == message.LevelResolved {
+		if !ok {
+			// the thread is unknown, such as after a restart, the resolution is posted to the channel
+			_, err := b.call(methodPostMessage, &botMessage{Channel: b.channel, payload: *newPayload(msg)})
+			return err
+		}
+
+		delete(parent.firing, msg.Alert)
+		if len(parent.firing) == 0 {
+			// every alert of the hpa is resolved, mark the parent message as resolved
+			if _, err := b.call(methodUpdate, &botMessage{Channel: parent.channel, TS: parent.ts, payload: *newPayload(msg)}); err != nil {
+				return err
+			}
+		}
+		if _, err := b.call(methodPostMessage, &botMessage{Channel: parent.channel, ThreadTS: parent.ts, payload: *newPayload(msg)}); err != nil {
+			return err
+		}
+		if len(parent.firing) == 0 {
+			// the thread is closed once the resolution is posted, a retry still finds it
+			delete(b.threads, k)
+		}
+
+		return nil
+	}
+
+	if ok {
+		parent.firing[msg.Alert] = struct{}{}
+		_, err := b.call(methodPostMessage, &botMessage{Channel: parent.channel, ThreadTS: parent.ts, payload: *newPayload(msg)})
+		return err
+	}
+
+	resp, err := b.call(methodPostMessage, &botMessage{Channel: b.channel, payload: *newPayload(msg)})
+	if err != nil {
+		return err
+	}
+	b.threads[k] = &thread{channel: resp.Channel, ts: resp.TS, firing: map[string]struct{}{msg.Alert: {}}}
+
+	return nil
+}
+
+// call invokes the slack web api method
+func (b *bot) call(method string, m *botMessage) (*botResponse, error) {
+	header := map[string]string{
+		"Content-Type":  "application/json; charset=utf-8",
+		"Authorization": "Bearer " + b.token,
+	}
+
+	var result botResponse
+	if err := reporter.PostJSON(b.client, b.apiURL+"/"+method, header, m, &result); err != nil {
+		return nil, fmt.Errorf("slack %s: %w", method, err)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("slack %s failed: %s", method, result.Error)
+	}
+	if result.Channel == "" {
+		result.Channel = m.Channel
+	}
+
+	return &result, nil
+}
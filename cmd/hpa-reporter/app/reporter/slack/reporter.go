@@ -4,45 +4,131 @@ import (
 	"fmt"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"net/http"
+	"time"
 )
 
+func init() {
+	reporter.Register(Type, func(cfg config.Reporter, shutdown chan struct{}) (reporter.Reporter, error) {
+		r, err := CreateReporter(cfg, shutdown)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+	reporter.RegisterValidator(Type, Validate)
+}
+
+const (
+	Type = "slack"
+
+	ConfigMode    = "mode"
+	ConfigURL     = "url"
+	ConfigToken   = "token"
+	ConfigChannel = "channel"
+	ConfigAPIURL  = "apiUrl"
+	ConfigTimeout = "timeout"
+
+	ModeWebhook = "webhook"
+	ModeBot     = "bot"
+
+	DefaultAPIURL  = "https://slack.com/api"
+	DefaultTimeout = 5 * time.Second
+)
+
+// sender delivers a message to slack
+type sender interface {
+	send(msg *message.Data) error
+}
+
 // Reporter is slack reporter
 type Reporter struct {
-	msgChan  chan *message.Data
 	shutdown chan struct{}
 
 	name    string
 	configs map[string]string
+
+	sender sender
 }
 
 // Report sends message to slack
-func (r *Reporter) Report(msg *message.Data) {
-	r.msgChan <- msg
-}
-
-// run starts the reporter
-func (r *Reporter) run() {
-LOOP:
-	for {
-		select {
-		case msg := <-r.msgChan:
-			// TODO implement slack reporter
-			fmt.Printf("slack(%s): %s[%s/%s]: replicas(%d/%d)\n", r.name, msg.Level, msg.Name, msg.Namespace, msg.CurrentReplicas, msg.MaxReplicas)
-		case <-r.shutdown:
-			break LOOP
+func (r *Reporter) Report(msg *message.Data) error {
+	return r.send(msg)
+}
+
+// send delivers the message with the configured sender
+func (r *Reporter) send(msg *message.Data) error {
+	return r.sender.send(msg)
+}
+
+// Validate checks the slack reporter config for the selected mode
+func Validate(cfg config.Reporter) []config.Problem {
+	var problems []config.Problem
+	required := func(keys ...string) {
+		for _, key := range keys {
+			if cfg.Configs[key] == "" {
+				problems = append(problems, config.Problem{Path: "configs." + key, Message: fmt.Sprintf("%s is required in %s mode", key, modeOf(cfg))})
+			}
+		}
+	}
+
+	switch mode := modeOf(cfg); mode {
+	case ModeWebhook:
+		required(ConfigURL)
+	case ModeBot:
+		required(ConfigToken, ConfigChannel)
+	default:
+		problems = append(problems, config.Problem{Path: "configs." + ConfigMode, Message: fmt.Sprintf("unsupported mode %q, use %s or %s", mode, ModeWebhook, ModeBot)})
+	}
+
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			problems = append(problems, config.Problem{Path: "configs." + ConfigTimeout, Message: fmt.Sprintf("invalid duration %q", v)})
 		}
 	}
+
+	return problems
+}
+
+// modeOf returns the configured mode, webhook if it is not set
+func modeOf(cfg config.Reporter) string {
+	if mode := cfg.Configs[ConfigMode]; mode != "" {
+		return mode
+	}
+
+	return ModeWebhook
 }
 
 // CreateReporter creates a new slack reporter
 func CreateReporter(cfg config.Reporter, shutdown chan struct{}) (*Reporter, error) {
+	if problems := Validate(cfg); len(problems) > 0 {
+		return nil, fmt.Errorf("slack reporter(%s): %w", cfg.Name, &config.ValidationError{Problems: problems})
+	}
+
+	timeout := DefaultTimeout
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		timeout, _ = time.ParseDuration(v)
+	}
+	client := &http.Client{Timeout: timeout}
+
 	r := &Reporter{
-		msgChan:  make(chan *message.Data),
 		shutdown: shutdown,
 		name:     cfg.Name,
 		configs:  cfg.Configs,
 	}
-	go r.run()
+
+	switch modeOf(cfg) {
+	case ModeWebhook:
+		r.sender = newWebhook(cfg.Configs[ConfigURL], client)
+	case ModeBot:
+		apiURL := cfg.Configs[ConfigAPIURL]
+		if apiURL == "" {
+			apiURL = DefaultAPIURL
+		}
+		threads := reporter.State(cfg, func() map[string]*thread { return make(map[string]*thread) })
+		r.sender = newBot(apiURL, cfg.Configs[ConfigToken], cfg.Configs[ConfigChannel], client, threads)
+	}
 
 	return r, nil
 }
@@ -0,0 +1,31 @@
+package slack
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"net/http"
+)
+
+// webhook sends messages to a slack incoming webhook
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+// newWebhook creates a new incoming webhook sender
+func newWebhook(url string, client *http.Client) *webhook {
+	return &webhook{
+		url:    url,
+		client: client,
+	}
+}
+
+// send posts the message to the slack incoming webhook
+func (w *webhook) send(msg *message.Data) error {
+	if err := reporter.PostJSON(w.client, w.url, nil, newPayload(msg), nil); err != nil {
+		return fmt.Errorf("slack webhook: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package slack
+
+import (
+	"encoding/json"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/reportertest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReporterSend(t *testing.T) {
+	var got payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{ConfigURL: srv.URL}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	err = r.send(&message.Data{
+		Level:           message.LevelCritical,
+		Name:            "my-hpa",
+		Namespace:       "test",
+		CurrentReplicas: 10,
+		MaxReplicas:     10,
+	})
+	if err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	if len(got.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment, got %d", len(got.Attachments))
+	}
+	if got.Attachments[0].Color != ColorCritical {
+		t.Errorf("expected color %s, got %s", ColorCritical, got.Attachments[0].Color)
+	}
+	if header := got.Attachments[0].Blocks[0]; header.Type != "header" || !strings.Contains(header.Text.Text, "test/my-hpa") {
+		t.Errorf("unexpected header block: %+v", header)
+	}
+}
+
+func TestReporterSendNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{ConfigURL: srv.URL}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	err = r.send(&message.Data{Level: message.LevelWarning, Name: "my-hpa", Namespace: "test"})
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected status 403 error, got %v", err)
+	}
+}
+
+func TestCreateReporterWithoutURL(t *testing.T) {
+	if _, err := CreateReporter(config.Reporter{Name: "test"}, make(chan struct{})); err == nil {
+		t.Error("expected error without url")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	reportertest.CheckValidate(t, Type, Validate, []reportertest.ValidateCase{
+		{Configs: map[string]string{ConfigURL: "http://example"}},
+		{Configs: map[string]string{}, Want: []string{"configs.url"}},
+		{Configs: map[string]string{ConfigMode: ModeBot, ConfigToken: "xoxb"}, Want: []string{"configs.channel"}},
+		{Configs: map[string]string{ConfigMode: "rtm"}, Want: []string{"configs.mode"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigTimeout: "soon"}, Want: []string{"configs.timeout"}},
+	})
+}
+
+func TestReplicaBar(t *testing.T) {
+	tests := []struct {
+		current, max int32
+		want         string
+	}{
+		{0, 10, "░░░░░░░░░░"},
+		{5, 10, "█████░░░░░"},
+		{10, 10, "██████████"},
+		{12, 10, "██████████"},
+	}
+
+	for _, tt := range tests {
+		if got := replicaBar(tt.current, tt.max); got != tt.want {
+			t.Errorf("replicaBar(%d, %d) = %s, want %s", tt.current, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestBotThread(t *testing.T) {
+	var calls []string
+	var messages []botMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("unexpected authorization header: %s", r.Header.Get("Authorization"))
+		}
+
+		var m botMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			t.Errorf("failed to decode message: %v", err)
+		}
+		calls = append(calls, strings.TrimPrefix(r.URL.Path, "/"))
+		messages = append(messages, m)
+
+		_ = json.NewEncoder(w).Encode(botResponse{OK: true, Channel: "C123", TS: "1700000000.000100"})
+	}))
+	defer srv.Close()
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{
+		ConfigMode:    ModeBot,
+		ConfigToken:   "xoxb-test",
+		ConfigChannel: "#alerts",
+		ConfigAPIURL:  srv.URL,
+	}}, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	for _, level := range []string{message.LevelWarning, message.LevelCritical, message.LevelResolved} {
+		if err = r.send(&message.Data{Level: level, Name: "my-hpa", Namespace: "test"}); err != nil {
+			t.Fatalf("failed to send %s message: %v", level, err)
+		}
+	}
+
+	wantCalls := []string{methodPostMessage, methodPostMessage, methodUpdate, methodPostMessage}
+	if strings.Join(calls, ",") != strings.Join(wantCalls, ",") {
+		t.Fatalf("expected calls %v, got %v", wantCalls, calls)
+	}
+	if messages[0].Channel != "#alerts" || messages[0].ThreadTS != "" {
+		t.Errorf("unexpected parent message: %+v", messages[0])
+	}
+	if messages[1].ThreadTS != "1700000000.000100" {
+		t.Errorf("expected reply in thread, got %+v", messages[1])
+	}
+	if messages[2].TS != "1700000000.000100" || messages[2].Attachments[0].Color != ColorResolved {
+		t.Errorf("expected parent message to be resolved, got %+v", messages[2])
+	}
+
+	// a new alert after resolution starts a new thread
+	calls = nil
+	if err = r.send(&message.Data{Level: message.LevelWarning, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	if len(messages) != 5 || messages[4].ThreadTS != "" {
+		t.Errorf("expected a new parent message, got %+v", messages[len(messages)-1])
+	}
+	if err = r.send(&message.Data{Level: message.LevelResolved, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send resolved message: %v", err)
+	}
+	if len(r.sender.(*bot).threads) != 0 {
+		t.Errorf("expected every thread to be closed, got %+v", r.sender.(*bot).threads)
+	}
+}
+
+func TestBotThreadReload(t *testing.T) {
+	srv := reportertest.NewServer(t, http.StatusOK, `{"ok":true,"channel":"C123","ts":"1700000000.000100"}`)
+
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+
+	cfg := config.Reporter{Name: "reload", Type: Type, Configs: map[string]string{
+		ConfigMode:    ModeBot,
+		ConfigToken:   "xoxb-test",
+		ConfigChannel: "#alerts",
+		ConfigAPIURL:  srv.URL,
+	}}
+	r, err := CreateReporter(cfg, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	if err = r.Report(&message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	// the reporter created on reload resolves the thread of the previous one
+	cfg.Configs[ConfigTimeout] = "10s"
+	reloaded, err := CreateReporter(cfg, shutdown)
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	if err = reloaded.Report(&message.Data{Level: message.LevelResolved, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send resolved message: %v", err)
+	}
+
+	// the resolution of an unknown thread is posted to the channel
+	if err = reloaded.Report(&message.Data{Level: message.LevelResolved, Alert: message.AlertReplicas, Name: "other", Namespace: "test"}); err != nil {
+		t.Fatalf("failed to send resolved message: %v", err)
+	}
+
+	var got []string
+	for _, req := range srv.Requests() {
+		var m botMessage
+		req.Decode(t, &m)
+		got = append(got, strings.TrimPrefix(req.Path, "/")+" "+m.TS+" "+m.ThreadTS)
+	}
+	want := []string{
+		methodPostMessage + "  ",
+		methodUpdate + " 1700000000.000100 ",
+		methodPostMessage + "  1700000000.000100",
+		methodPostMessage + "  ",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected calls %q, got %q", want, got)
+	}
+}
+
+func TestNewPayloadResolved(t *testing.T) {
+	p := newPayload(&message.Data{Level: message.LevelResolved, Name: "my-hpa", Namespace: "test", Duration: 90 * time.Second})
+
+	if p.Attachments[0].Color != ColorResolved {
+		t.Errorf("expected color %s, got %s", ColorResolved, p.Attachments[0].Color)
+	}
+
+	var found bool
+	for _, b := range p.Attachments[0].Blocks {
+		if b.Text != nil && strings.Contains(b.Text.Text, "Resolved after") && strings.Contains(b.Text.Text, "1m30s") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected resolved duration block, got %+v", p.Attachments[0].Blocks)
+	}
+}
@@ -0,0 +1,48 @@
+package reporter
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"sync"
+)
+
+var (
+	statesMu sync.Mutex
+	// states is keyed by type/name of the reporter
+	states = make(map[string]interface{})
+)
+
+// State returns the state of the reporter, newState creates it if the reporter has none yet.
+// a reporter created again on config reload with the same name and type gets the state of the previous one,
+// such as the open alerts it has to resolve. the handler starts the new reporter after the previous one stopped,
+// so a state used only by the queue worker of the reporter needs no lock. a reload that removes the reporter drops it.
+func State[T any](cfg config.Reporter, newState func() T) T {
+	statesMu.Lock()
+	defer statesMu.Unlock()
+
+	key := cfg.Type + "/" + cfg.Name
+	if s, ok := states[key].(T); ok {
+		return s
+	}
+
+	s := newState()
+	states[key] = s
+	return s
+}
+
+// dropStates forgets the state of the reporters that are not in the config, a reporter added again later starts
+// without the alerts of the removed one
+func dropStates(reporters []config.Reporter) {
+	keep := make(map[string]struct{}, len(reporters))
+	for _, cfg := range reporters {
+		keep[cfg.Type+"/"+cfg.Name] = struct{}{}
+	}
+
+	statesMu.Lock()
+	defer statesMu.Unlock()
+
+	for key := range states {
+		if _, ok := keep[key]; !ok {
+			delete(states, key)
+		}
+	}
+}
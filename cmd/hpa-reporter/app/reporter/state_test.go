@@ -0,0 +1,43 @@
+package reporter
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"testing"
+)
+
+func TestState(t *testing.T) {
+	newState := func() map[string]int { return make(map[string]int) }
+
+	s := State(config.Reporter{Name: "state", Type: "fake"}, newState)
+	s["test/my-hpa"] = 1
+
+	if got := State(config.Reporter{Name: "state", Type: "fake", Configs: map[string]string{"url": "http://a"}}, newState); got["test/my-hpa"] != 1 {
+		t.Error("expected the reporter created again to get the state")
+	}
+	if got := State(config.Reporter{Name: "state", Type: "other"}, newState); len(got) != 0 {
+		t.Errorf("expected a new state for another type, got %v", got)
+	}
+}
+
+func TestReloadDropsState(t *testing.T) {
+	h, err := NewReporterHandler([]config.Reporter{{Name: "a", Type: "fake"}, {Name: "b", Type: "fake"}}, nil)
+	if err != nil {
+		t.Fatalf("failed to create reporter handler: %v", err)
+	}
+	defer h.Drain(0)
+
+	newState := func() map[string]int { return make(map[string]int) }
+	State(config.Reporter{Name: "a", Type: "fake"}, newState)["test/my-hpa"] = 1
+	State(config.Reporter{Name: "b", Type: "fake"}, newState)["test/my-hpa"] = 1
+
+	if err = h.Reload([]config.Reporter{{Name: "b", Type: "fake"}}, nil); err != nil {
+		t.Fatalf("failed to reload: %v", err)
+	}
+	// a reporter added again does not resume the alerts of the removed one
+	if got := State(config.Reporter{Name: "a", Type: "fake"}, newState); len(got) != 0 {
+		t.Errorf("expected the state of the removed reporter to be dropped, got %v", got)
+	}
+	if got := State(config.Reporter{Name: "b", Type: "fake"}, newState); got["test/my-hpa"] != 1 {
+		t.Error("expected the state of the kept reporter")
+	}
+}
@@ -4,44 +4,59 @@ import (
 	"fmt"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
 	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"time"
 )
 
+const (
+	Type = "stdout"
+)
+
+func init() {
+	reporter.Register(Type, func(cfg config.Reporter, shutdown chan struct{}) (reporter.Reporter, error) {
+		r, err := CreateReporter(cfg, shutdown)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+}
+
 // Reporter is stdout reporter
 type Reporter struct {
-	msgChan  chan *message.Data
 	shutdown chan struct{}
 
 	name    string
 	configs map[string]string
 }
 
-// Report sends message to stdout
-func (r *Reporter) Report(msg *message.Data) {
-	r.msgChan <- msg
+// Report writes message to stdout
+func (r *Reporter) Report(msg *message.Data) error {
+	_, err := fmt.Println(r.format(msg))
+	return err
 }
 
-// run starts the reporter
-func (r *Reporter) run() {
-LOOP:
-	for {
-		select {
-		case msg := <-r.msgChan:
-			fmt.Printf("stdout(%s): %s[%s/%s]: replicas(%d/%d)\n", r.name, msg.Level, msg.Name, msg.Namespace, msg.CurrentReplicas, msg.MaxReplicas)
-		case <-r.shutdown:
-			break LOOP
-		}
+// format renders the message as a single line
+func (r *Reporter) format(msg *message.Data) string {
+	line := fmt.Sprintf("stdout(%s): %s[%s/%s]: %s: replicas(current %d, desired %d, max %d)",
+		r.name, msg.Level, msg.Name, msg.Namespace, msg.Summary(), msg.CurrentReplicas, msg.DesiredReplicas, msg.MaxReplicas)
+	if msg.ConditionReason != "" {
+		line += fmt.Sprintf(" reason(%s): %s", msg.ConditionReason, msg.ConditionMessage)
 	}
+	if msg.Level == message.LevelResolved {
+		line += fmt.Sprintf(" after %s", msg.Duration.Round(time.Second))
+	}
+
+	return line
 }
 
 // CreateReporter creates a new stdout reporter
 func CreateReporter(cfg config.Reporter, shutdown chan struct{}) (*Reporter, error) {
 	r := &Reporter{
-		msgChan:  make(chan *message.Data),
 		shutdown: shutdown,
 		name:     cfg.Name,
 		configs:  cfg.Configs,
 	}
-	go r.run()
 
 	return r, nil
 }
@@ -0,0 +1,150 @@
+package teams
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// adaptive card container styles and text colours of the levels
+	StyleWarning  = "warning"
+	StyleCritical = "attention"
+	StyleResolved = "good"
+	StyleDefault  = "accent"
+
+	cardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	cardVersion     = "1.4"
+	cardContentType = "application/vnd.microsoft.card.adaptive"
+)
+
+type (
+	// payload is the message of a teams workflow or incoming webhook
+	payload struct {
+		Type        string       `json:"type"`
+		Attachments []attachment `json:"attachments"`
+	}
+
+	// attachment wraps the adaptive card
+	attachment struct {
+		ContentType string `json:"contentType"`
+		Content     card   `json:"content"`
+	}
+
+	// card is an adaptive card
+	card struct {
+		Schema  string                 `json:"$schema"`
+		Type    string                 `json:"type"`
+		Version string                 `json:"version"`
+		Body    []element              `json:"body"`
+		Actions []action               `json:"actions,omitempty"`
+		MSTeams map[string]interface{} `json:"msteams,omitempty"`
+	}
+
+	// element is an adaptive card element, only the fields of the used types are set
+	element struct {
+		Type     string    `json:"type"`
+		Style    string    `json:"style,omitempty"`
+		Bleed    bool      `json:"bleed,omitempty"`
+		Items    []element `json:"items,omitempty"`
+		Text     string    `json:"text,omitempty"`
+		Size     string    `json:"size,omitempty"`
+		Weight   string    `json:"weight,omitempty"`
+		Color    string    `json:"color,omitempty"`
+		IsSubtle bool      `json:"isSubtle,omitempty"`
+		Wrap     bool      `json:"wrap,omitempty"`
+		Facts    []fact    `json:"facts,omitempty"`
+	}
+
+	// fact is a title and value of a FactSet
+	fact struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+	}
+
+	// action is an adaptive card action
+	action struct {
+		Type  string `json:"type"`
+		Title string `json:"title"`
+		URL   string `json:"url"`
+	}
+)
+
+// newPayload builds the adaptive card message from message data.
+// workloadURL is the link of the workload, {namespace}, {kind}, {name} and {hpa} are replaced.
+func newPayload(msg *message.Data, workloadURL string) *payload {
+	style := styleOf(msg.Level)
+	title := fmt.Sprintf("HPA %s: %s/%s", strings.ToUpper(msg.Level), msg.Namespace, msg.Name)
+
+	facts := []fact{
+		{Title: "Namespace", Value: msg.Namespace},
+		{Title: "HPA", Value: msg.Name},
+	}
+	if msg.TargetName != "" {
+		facts = append(facts, fact{Title: "Workload", Value: msg.TargetKind + "/" + msg.TargetName})
+	}
+	facts = append(facts,
+		fact{Title: "Replicas", Value: fmt.Sprintf("%d / %d (desired %d)", msg.CurrentReplicas, msg.MaxReplicas, msg.DesiredReplicas)},
+	)
+	if msg.ConditionReason != "" {
+		facts = append(facts, fact{Title: "Condition", Value: fmt.Sprintf("%s: %s", msg.ConditionReason, msg.ConditionMessage)})
+	}
+	if msg.Level == message.LevelResolved {
+		facts = append(facts, fact{Title: "Resolved after", Value: msg.Duration.Round(time.Second).String()})
+	}
+	if msg.Time != "" {
+		facts = append(facts, fact{Title: "Last scale time", Value: msg.Time})
+	}
+
+	c := card{
+		Schema:  cardSchema,
+		Type:    "AdaptiveCard",
+		Version: cardVersion,
+		Body: []element{
+			{Type: "Container", Style: style, Bleed: true, Items: []element{
+				{Type: "TextBlock", Text: title, Size: "Large", Weight: "Bolder", Color: style, Wrap: true},
+				{Type: "TextBlock", Text: msg.Summary(), IsSubtle: true, Wrap: true},
+			}},
+			{Type: "FactSet", Facts: facts},
+		},
+		MSTeams: map[string]interface{}{"width": "Full"},
+	}
+	if link := workloadLink(workloadURL, msg); link != "" {
+		c.Actions = []action{{Type: "Action.OpenUrl", Title: "Open workload", URL: link}}
+	}
+
+	return &payload{
+		Type:        "message",
+		Attachments: []attachment{{ContentType: cardContentType, Content: c}},
+	}
+}
+
+// workloadLink replaces the placeholders of the workload url with the escaped message values
+func workloadLink(workloadURL string, msg *message.Data) string {
+	if workloadURL == "" {
+		return ""
+	}
+
+	return strings.NewReplacer(
+		"{namespace}", url.PathEscape(msg.Namespace),
+		"{kind}", url.PathEscape(strings.ToLower(msg.TargetKind)),
+		"{name}", url.PathEscape(msg.TargetName),
+		"{hpa}", url.PathEscape(msg.Name),
+	).Replace(workloadURL)
+}
+
+// styleOf returns the container style of the level
+func styleOf(level string) string {
+	switch level {
+	case message.LevelWarning:
+		return StyleWarning
+	case message.LevelCritical:
+		return StyleCritical
+	case message.LevelResolved:
+		return StyleResolved
+	default:
+		return StyleDefault
+	}
+}
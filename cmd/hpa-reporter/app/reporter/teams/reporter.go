@@ -0,0 +1,78 @@
+package teams
+
+import (
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"net/http"
+	"time"
+)
+
+func init() {
+	reporter.Register(Type, func(cfg config.Reporter, _ chan struct{}) (reporter.Reporter, error) {
+		r, err := CreateReporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+	reporter.RegisterValidator(Type, Validate)
+}
+
+const (
+	Type = "teams"
+
+	ConfigURL         = "url"
+	ConfigTimeout     = "timeout"
+	ConfigWorkloadURL = "workloadUrl"
+
+	DefaultTimeout = 5 * time.Second
+)
+
+// Reporter posts adaptive cards to a teams workflow or incoming webhook url
+type Reporter struct {
+	url         string
+	workloadURL string
+	client      *http.Client
+}
+
+// Report posts the adaptive card of the message to the webhook url
+func (r *Reporter) Report(msg *message.Data) error {
+	return reporter.PostJSON(r.client, r.url, nil, newPayload(msg, r.workloadURL), nil)
+}
+
+// Validate checks the teams reporter config
+func Validate(cfg config.Reporter) []config.Problem {
+	var problems []config.Problem
+
+	if cfg.Configs[ConfigURL] == "" {
+		problems = append(problems, config.Problem{Path: "configs." + ConfigURL, Message: fmt.Sprintf("%s is required", ConfigURL)})
+	}
+
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			problems = append(problems, config.Problem{Path: "configs." + ConfigTimeout, Message: fmt.Sprintf("invalid duration %q", v)})
+		}
+	}
+
+	return problems
+}
+
+// CreateReporter creates a new teams reporter
+func CreateReporter(cfg config.Reporter) (*Reporter, error) {
+	if problems := Validate(cfg); len(problems) > 0 {
+		return nil, fmt.Errorf("teams reporter(%s): %w", cfg.Name, &config.ValidationError{Problems: problems})
+	}
+
+	timeout := DefaultTimeout
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		timeout, _ = time.ParseDuration(v)
+	}
+
+	return &Reporter{
+		url:         cfg.Configs[ConfigURL],
+		workloadURL: cfg.Configs[ConfigWorkloadURL],
+		client:      &http.Client{Timeout: timeout},
+	}, nil
+}
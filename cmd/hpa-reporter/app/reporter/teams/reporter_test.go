@@ -0,0 +1,83 @@
+package teams
+
+import (
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/reportertest"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	// teams workflows accept the message with 202
+	srv := reportertest.NewServer(t, http.StatusAccepted, "")
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{
+		ConfigURL:         srv.URL,
+		ConfigWorkloadURL: "https://console.example/ns/{namespace}/{kind}/{name}",
+	}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	err = r.Report(&message.Data{
+		Level:           message.LevelCritical,
+		Name:            "my-hpa",
+		Namespace:       "test",
+		TargetKind:      "Deployment",
+		TargetName:      "my-app",
+		CurrentReplicas: 10,
+		MaxReplicas:     10,
+	})
+	if err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	var got payload
+	srv.Requests()[0].Decode(t, &got)
+	if got.Type != "message" || len(got.Attachments) != 1 || got.Attachments[0].ContentType != cardContentType {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+	c := got.Attachments[0].Content
+	if c.Type != "AdaptiveCard" || c.Body[0].Style != StyleCritical {
+		t.Errorf("expected %s card, got %+v", StyleCritical, c)
+	}
+	if header := c.Body[0].Items[0]; !strings.Contains(header.Text, "test/my-hpa") {
+		t.Errorf("unexpected header: %+v", header)
+	}
+	if len(c.Actions) != 1 || c.Actions[0].URL != "https://console.example/ns/test/deployment/my-app" {
+		t.Errorf("unexpected workload link: %+v", c.Actions)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	reportertest.CheckValidate(t, Type, Validate, []reportertest.ValidateCase{
+		{Configs: map[string]string{ConfigURL: "http://example"}},
+		{Configs: map[string]string{}, Want: []string{"configs.url"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigTimeout: "soon"}, Want: []string{"configs.timeout"}},
+	})
+}
+
+func TestNewPayloadResolved(t *testing.T) {
+	p := newPayload(&message.Data{Level: message.LevelResolved, Name: "my-hpa", Namespace: "test", Duration: 90 * time.Second}, "")
+	c := p.Attachments[0].Content
+
+	if c.Body[0].Style != StyleResolved {
+		t.Errorf("expected style %s, got %s", StyleResolved, c.Body[0].Style)
+	}
+	if len(c.Actions) != 0 {
+		t.Errorf("expected no workload link without workloadUrl, got %+v", c.Actions)
+	}
+
+	var found bool
+	for _, f := range c.Body[1].Facts {
+		if f.Title == "Resolved after" && f.Value == "1m30s" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected resolved duration fact, got %+v", c.Body[1].Facts)
+	}
+}
@@ -0,0 +1,296 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+func init() {
+	reporter.Register(Type, func(cfg config.Reporter, _ chan struct{}) (reporter.Reporter, error) {
+		r, err := CreateReporter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	})
+	reporter.RegisterValidator(Type, Validate)
+}
+
+const (
+	Type = "webhook"
+
+	ConfigURL          = "url"
+	ConfigMethod       = "method"
+	ConfigTimeout      = "timeout"
+	ConfigContentType  = "contentType"
+	ConfigTemplate     = "template"
+	ConfigTemplateFile = "templateFile"
+	ConfigDryRun       = "dryRun"
+	// ConfigHeaderPrefix sets a request header, such as "header.X-Team: payments"
+	ConfigHeaderPrefix = "header."
+
+	ConfigAuth       = "auth"
+	ConfigUsername   = "username"
+	ConfigPassword   = "password"
+	ConfigToken      = "token"
+	ConfigHMACSecret = "hmacSecret"
+	ConfigHMACHeader = "hmacHeader"
+
+	AuthNone   = "none"
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthHMAC   = "hmac"
+
+	DefaultMethod      = http.MethodPost
+	DefaultTimeout     = 5 * time.Second
+	DefaultContentType = "application/json"
+	DefaultHMACHeader  = "X-Signature-256"
+
+	// masked replaces the secrets in the dry-run output
+	masked = "***"
+
+	// DefaultTemplate renders the message as a flat json object
+	DefaultTemplate = `{
+  "level": {{ json .Level }},
+  "alert": {{ json .Alert }},
+  "summary": {{ json .Summary }},
+  "namespace": {{ json .Namespace }},
+  "name": {{ json .Name }},
+  "currentReplicas": {{ .CurrentReplicas }},
+  "desiredReplicas": {{ .DesiredReplicas }},
+  "maxReplicas": {{ .MaxReplicas }},
+  "reason": {{ json .ConditionReason }},
+  "message": {{ json .ConditionMessage }},
+  "lastScaleTime": {{ json .Time }}
+}`
+)
+
+// Reporter posts a templated body to a http endpoint
+type Reporter struct {
+	name    string
+	configs map[string]string
+
+	url         string
+	method      string
+	contentType string
+	headers     map[string]string
+	auth        string
+	tmpl        *template.Template
+	dryRun      bool
+	out         io.Writer
+	client      *http.Client
+}
+
+// Report renders the message and sends it to the endpoint, or prints it in dry-run mode
+func (r *Reporter) Report(msg *message.Data) error {
+	var body bytes.Buffer
+	if err := r.tmpl.Execute(&body, msg); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	req, err := http.NewRequest(r.method, r.url, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", r.contentType)
+	for k, v := range r.headers {
+		req.Header.Set(k, v)
+	}
+	r.authenticate(req, body.Bytes())
+
+	if r.dryRun {
+		return r.print(req, body.Bytes())
+	}
+
+	return reporter.Send(r.client, req, nil)
+}
+
+// authenticate sets the credentials of the configured auth on the request
+func (r *Reporter) authenticate(req *http.Request, body []byte) {
+	switch r.auth {
+	case AuthBasic:
+		req.SetBasicAuth(r.configs[ConfigUsername], r.configs[ConfigPassword])
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+r.configs[ConfigToken])
+	case AuthHMAC:
+		mac := hmac.New(sha256.New, []byte(r.configs[ConfigHMACSecret]))
+		mac.Write(body)
+		header := r.configs[ConfigHMACHeader]
+		if header == "" {
+			header = DefaultHMACHeader
+		}
+		req.Header.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+}
+
+// print writes the request that would be sent. the configured headers, the auth headers and the
+// userinfo and query of the url may hold resolved secrets, so only their names are printed.
+func (r *Reporter) print(req *http.Request, body []byte) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "webhook(%s) dry-run: %s %s\n", r.name, req.Method, maskURL(req.URL))
+
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := masked
+		if k == "Content-Type" {
+			v = req.Header.Get(k)
+		}
+		fmt.Fprintf(&b, "%s: %s\n", k, v)
+	}
+	fmt.Fprintf(&b, "\n%s\n", body)
+
+	_, err := io.WriteString(r.out, b.String())
+	return err
+}
+
+// maskURL returns the url with the userinfo and the query values masked
+func maskURL(u *url.URL) string {
+	c := *u
+	var userinfo string
+	if c.User != nil {
+		// url.User would escape the mask
+		c.User, userinfo = nil, masked+"@"
+	}
+	if c.RawQuery != "" {
+		query := c.Query()
+		keys := make([]string, 0, len(query))
+		for k := range query {
+			keys = append(keys, url.QueryEscape(k)+"="+masked)
+		}
+		sort.Strings(keys)
+		c.RawQuery = strings.Join(keys, "&")
+	}
+
+	return c.Scheme + "://" + userinfo + strings.TrimPrefix(c.String(), c.Scheme+"://")
+}
+
+// Validate checks the webhook reporter config
+func Validate(cfg config.Reporter) []config.Problem {
+	var problems []config.Problem
+	problem := func(key, format string, args ...interface{}) {
+		problems = append(problems, config.Problem{Path: "configs." + key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if u := cfg.Configs[ConfigURL]; u == "" {
+		problem(ConfigURL, "%s is required", ConfigURL)
+	} else if parsed, err := url.Parse(u); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		if !config.HasReference(u) {
+			problem(ConfigURL, "invalid url %q", u)
+		}
+	}
+
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			problem(ConfigTimeout, "invalid duration %q", v)
+		}
+	}
+	if v, ok := cfg.Configs[ConfigDryRun]; ok {
+		if _, err := strconv.ParseBool(v); err != nil {
+			problem(ConfigDryRun, "invalid bool %q", v)
+		}
+	}
+
+	if cfg.Configs[ConfigTemplate] != "" && cfg.Configs[ConfigTemplateFile] != "" {
+		problem(ConfigTemplateFile, "only one of %s and %s can be set", ConfigTemplate, ConfigTemplateFile)
+	}
+	if text := cfg.Configs[ConfigTemplate]; text != "" {
+		if _, err := newTemplate(text); err != nil {
+			problem(ConfigTemplate, "%v", err)
+		}
+	}
+
+	required := func(keys ...string) {
+		for _, key := range keys {
+			if cfg.Configs[key] == "" {
+				problem(key, "%s is required with %s auth", key, cfg.Configs[ConfigAuth])
+			}
+		}
+	}
+	switch auth := cfg.Configs[ConfigAuth]; auth {
+	case "", AuthNone:
+	case AuthBasic:
+		required(ConfigUsername, ConfigPassword)
+	case AuthBearer:
+		required(ConfigToken)
+	case AuthHMAC:
+		required(ConfigHMACSecret)
+	default:
+		problem(ConfigAuth, "unsupported auth %q, use %s, %s, %s or %s", auth, AuthNone, AuthBasic, AuthBearer, AuthHMAC)
+	}
+
+	return problems
+}
+
+// CreateReporter creates a new webhook reporter
+func CreateReporter(cfg config.Reporter) (*Reporter, error) {
+	if problems := Validate(cfg); len(problems) > 0 {
+		return nil, fmt.Errorf("webhook reporter(%s): %w", cfg.Name, &config.ValidationError{Problems: problems})
+	}
+
+	text := cfg.Configs[ConfigTemplate]
+	if file := cfg.Configs[ConfigTemplateFile]; file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("webhook reporter(%s): failed to read %s: %w", cfg.Name, ConfigTemplateFile, err)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := newTemplate(text)
+	if err != nil {
+		return nil, fmt.Errorf("webhook reporter(%s): %w", cfg.Name, err)
+	}
+
+	timeout := DefaultTimeout
+	if v, ok := cfg.Configs[ConfigTimeout]; ok {
+		timeout, _ = time.ParseDuration(v)
+	}
+
+	r := &Reporter{
+		name:        cfg.Name,
+		configs:     cfg.Configs,
+		url:         cfg.Configs[ConfigURL],
+		method:      strings.ToUpper(cfg.Configs[ConfigMethod]),
+		contentType: cfg.Configs[ConfigContentType],
+		headers:     make(map[string]string),
+		auth:        cfg.Configs[ConfigAuth],
+		tmpl:        tmpl,
+		out:         os.Stdout,
+		client:      &http.Client{Timeout: timeout},
+	}
+	if r.method == "" {
+		r.method = DefaultMethod
+	}
+	if r.contentType == "" {
+		r.contentType = DefaultContentType
+	}
+	r.dryRun, _ = strconv.ParseBool(cfg.Configs[ConfigDryRun])
+	for k, v := range cfg.Configs {
+		if strings.HasPrefix(k, ConfigHeaderPrefix) {
+			r.headers[strings.TrimPrefix(k, ConfigHeaderPrefix)] = v
+		}
+	}
+
+	return r, nil
+}
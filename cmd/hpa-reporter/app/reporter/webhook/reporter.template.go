@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// funcs are the helper functions of the body template
+var funcs = template.FuncMap{
+	// json encodes the value, strings are quoted and escaped
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper":     strings.ToUpper,
+	"lower":     strings.ToLower,
+	"title":     title,
+	"trim":      strings.TrimSpace,
+	"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"join":      func(sep string, list []string) string { return strings.Join(list, sep) },
+	"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	// default returns def if the value is empty
+	"default": func(def, v interface{}) interface{} {
+		if v == nil || v == "" || v == 0 || v == int32(0) {
+			return def
+		}
+		return v
+	},
+	// percent returns part of total in percent, rounded down
+	"percent": func(part, total int32) int32 {
+		if total <= 0 {
+			return 0
+		}
+		return part * 100 / total
+	},
+	// duration formats a duration rounded to seconds, such as "1h2m3s"
+	"duration": func(d time.Duration) string { return d.Round(time.Second).String() },
+	// now returns the current time in RFC 3339
+	"now": func() string { return time.Now().UTC().Format(time.RFC3339) },
+	// label returns the value of the key in a label or annotation map
+	"label": func(key string, m map[string]string) string { return m[key] },
+}
+
+// newTemplate parses the body template with the helper functions
+func newTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("body").Funcs(funcs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// title upper cases the first letter of the string
+func title(s string) string {
+	if s == "" {
+		return s
+	}
+
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,259 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/config"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/reporter/reportertest"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testMessage() *message.Data {
+	return &message.Data{
+		Level:           message.LevelCritical,
+		Alert:           message.AlertReplicas,
+		Name:            "my-hpa",
+		Namespace:       "test",
+		Labels:          map[string]string{"team": "payments"},
+		CurrentReplicas: 8,
+		DesiredReplicas: 10,
+		MaxReplicas:     10,
+	}
+}
+
+func TestReporterDefaultTemplate(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("expected POST, got %s", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != DefaultContentType {
+			t.Errorf("expected content type %s, got %s", DefaultContentType, ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("default template is not valid json: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{ConfigURL: srv.URL}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	if err = r.Report(testMessage()); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if got["level"] != message.LevelCritical || got["name"] != "my-hpa" || got["currentReplicas"] != float64(8) {
+		t.Errorf("unexpected payload: %v", got)
+	}
+}
+
+func TestReporterTemplateAndHeaders(t *testing.T) {
+	var body, team string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("expected PUT, got %s", r.Method)
+		}
+		team = r.Header.Get("X-Team")
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+	defer srv.Close()
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{
+		ConfigURL:                     srv.URL,
+		ConfigMethod:                  "put",
+		ConfigContentType:             "text/plain",
+		ConfigHeaderPrefix + "X-Team": "platform",
+		ConfigTemplate:                `{{ upper .Level }} {{ .Namespace }}/{{ .Name }} {{ percent .CurrentReplicas .MaxReplicas }}% {{ label "team" .Labels }}`,
+	}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	if err = r.Report(testMessage()); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if want := "CRITICAL test/my-hpa 80% payments"; body != want {
+		t.Errorf("expected body %q, got %q", want, body)
+	}
+	if team != "platform" {
+		t.Errorf("expected X-Team header platform, got %q", team)
+	}
+}
+
+func TestReporterAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs map[string]string
+		check   func(r *http.Request, body []byte) bool
+	}{
+		{
+			name:    "basic",
+			configs: map[string]string{ConfigAuth: AuthBasic, ConfigUsername: "user", ConfigPassword: "pass"},
+			check: func(r *http.Request, _ []byte) bool {
+				user, pass, ok := r.BasicAuth()
+				return ok && user == "user" && pass == "pass"
+			},
+		},
+		{
+			name:    "bearer",
+			configs: map[string]string{ConfigAuth: AuthBearer, ConfigToken: "secret-token"},
+			check: func(r *http.Request, _ []byte) bool {
+				return r.Header.Get("Authorization") == "Bearer secret-token"
+			},
+		},
+		{
+			name:    "hmac",
+			configs: map[string]string{ConfigAuth: AuthHMAC, ConfigHMACSecret: "shared", ConfigHMACHeader: "X-Hub-Signature-256"},
+			check: func(r *http.Request, body []byte) bool {
+				mac := hmac.New(sha256.New, []byte("shared"))
+				mac.Write(body)
+				return r.Header.Get("X-Hub-Signature-256") == "sha256="+hex.EncodeToString(mac.Sum(nil))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if !tt.check(r, body) {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			}))
+			defer srv.Close()
+
+			tt.configs[ConfigURL] = srv.URL
+			r, err := CreateReporter(config.Reporter{Name: "test", Configs: tt.configs})
+			if err != nil {
+				t.Fatalf("failed to create reporter: %v", err)
+			}
+			if err = r.Report(testMessage()); err != nil {
+				t.Errorf("expected authenticated request, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReporterNon2xx(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{ConfigURL: srv.URL}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+
+	var httpErr *reporter.HTTPError
+	err = r.Report(testMessage())
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests || httpErr.RetryAfter != 3*time.Second {
+		t.Errorf("expected retryable http error, got %v", err)
+	}
+}
+
+func TestReporterDryRun(t *testing.T) {
+	var called bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	r, err := CreateReporter(config.Reporter{Name: "test", Configs: map[string]string{
+		ConfigURL:                        strings.Replace(srv.URL, "http://", "http://user:url-password@", 1) + "/hook?token=query-token",
+		ConfigHeaderPrefix + "X-Api-Key": "header-key",
+		ConfigDryRun:                     "true",
+		ConfigAuth:                       AuthBearer,
+		ConfigToken:                      "secret-token",
+		ConfigTemplate:                   `{{ .Namespace }}/{{ .Name }}`,
+	}})
+	if err != nil {
+		t.Fatalf("failed to create reporter: %v", err)
+	}
+	var out bytes.Buffer
+	r.out = &out
+
+	if err = r.Report(testMessage()); err != nil {
+		t.Fatalf("failed to report: %v", err)
+	}
+	if called {
+		t.Error("expected no request in dry-run mode")
+	}
+	printed := out.String()
+	if !strings.Contains(printed, "POST http://***@"+strings.TrimPrefix(srv.URL, "http://")+"/hook?token=***") || !strings.Contains(printed, "test/my-hpa") {
+		t.Errorf("expected rendered request, got %q", printed)
+	}
+	if !strings.Contains(printed, "X-Api-Key: ***") || !strings.Contains(printed, "Content-Type: "+DefaultContentType) {
+		t.Errorf("expected header names to be printed, got %q", printed)
+	}
+	for _, secret := range []string{"secret-token", "header-key", "url-password", "query-token"} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("expected %s to be masked, got %q", secret, printed)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	reportertest.CheckValidate(t, Type, Validate, []reportertest.ValidateCase{
+		{Configs: map[string]string{ConfigURL: "http://example"}},
+		{Configs: map[string]string{ConfigURL: "${env:WEBHOOK_URL}"}},
+		{Configs: map[string]string{}, Want: []string{"configs.url"}},
+		{Configs: map[string]string{ConfigURL: "example"}, Want: []string{"configs.url"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigTemplate: "{{ .Name "}, Want: []string{"configs.template"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigTemplate: "{{ unknown .Name }}"}, Want: []string{"configs.template"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigTemplate: "a", ConfigTemplateFile: "b"}, Want: []string{"configs.templateFile"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigAuth: AuthBasic}, Want: []string{"configs.username", "configs.password"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigAuth: AuthHMAC}, Want: []string{"configs.hmacSecret"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigAuth: "digest"}, Want: []string{"configs.auth"}},
+		{Configs: map[string]string{ConfigURL: "http://example", ConfigDryRun: "maybe", ConfigTimeout: "0s"}, Want: []string{"configs.timeout", "configs.dryRun"}},
+	})
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{{ json .ConditionMessage }}`, `"say \"hi\""`},
+		{`{{ title .Level }}`, "Critical"},
+		{`{{ default "none" .ConditionReason }}`, "none"},
+		{`{{ duration .Duration }}`, "1m31s"},
+		{`{{ join "," .Receivers }}`, "a,b"},
+		{`{{ replace "-" "_" .Name | upper }}`, "MY_HPA"},
+		{`{{ if hasPrefix "my-" .Name }}yes{{ end }}`, "yes"},
+	}
+
+	msg := testMessage()
+	msg.ConditionMessage = `say "hi"`
+	msg.Duration = 90*time.Second + 700*time.Millisecond
+	msg.Receivers = []string{"a", "b"}
+
+	for _, tt := range tests {
+		tmpl, err := newTemplate(tt.text)
+		if err != nil {
+			t.Fatalf("newTemplate(%s) failed: %v", tt.text, err)
+		}
+		var b strings.Builder
+		if err = tmpl.Execute(&b, msg); err != nil {
+			t.Fatalf("execute %s failed: %v", tt.text, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s = %q, want %q", tt.text, b.String(), tt.want)
+		}
+	}
+}
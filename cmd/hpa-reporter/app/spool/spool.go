@@ -0,0 +1,231 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is a message that a reporter could not deliver
+type Entry struct {
+	ID          string        `json:"id"`
+	Reporter    string        `json:"reporter"`
+	Message     *message.Data `json:"message"`
+	Error       string        `json:"error"`
+	Attempts    int           `json:"attempts"`
+	FailedAt    time.Time     `json:"failedAt"`
+	LastAttempt time.Time     `json:"lastAttempt"`
+}
+
+// Key identifies the alert of a message for the reporter, a newer message with the same key supersedes it
+func Key(reporter string, msg *message.Data) string {
+	return reporter + "/" + msg.Namespace + "/" + msg.Name + "/" + msg.Alert
+}
+
+// Latest returns the failure time of the newest entry of every key
+func Latest(entries []Entry) map[string]time.Time {
+	latest := make(map[string]time.Time)
+	for _, e := range entries {
+		key := Key(e.Reporter, e.Message)
+		if e.FailedAt.After(latest[key]) {
+			latest[key] = e.FailedAt
+		}
+	}
+
+	return latest
+}
+
+// Spool is a JSONL file of undeliverable messages. the file is locked for every access,
+// so the spool commands can run next to the reporter.
+type Spool struct {
+	mu   sync.Mutex
+	path string
+	now  func() time.Time
+}
+
+// Open creates the directory of the spool file if it does not exist
+func Open(path string) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	return &Spool{path: path, now: time.Now}, nil
+}
+
+// Path returns the spool file path
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Add stores a message that the reporter failed to deliver
+func (s *Spool) Add(reporter string, msg *message.Data, cause error) (Entry, error) {
+	now := s.now()
+	entry := Entry{
+		ID:          fmt.Sprintf("%d-%04x", now.UnixNano(), rand.Intn(0x10000)),
+		Reporter:    reporter,
+		Message:     msg,
+		Error:       cause.Error(),
+		Attempts:    1,
+		FailedAt:    now,
+		LastAttempt: now,
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to marshal spool entry: %w", err)
+	}
+
+	err = s.locked(func() error {
+		f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		if _, err = f.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		return f.Sync()
+	})
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to write spool: %w", err)
+	}
+
+	return entry, nil
+}
+
+// List returns every entry in the order they were added
+func (s *Spool) List() ([]Entry, error) {
+	var entries []Entry
+	err := s.locked(func() error {
+		var err error
+		entries, err = s.read()
+		return err
+	})
+
+	return entries, err
+}
+
+// Remove deletes the entries with the ids and returns how many were removed
+func (s *Spool) Remove(ids ...string) (int, error) {
+	remove := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		remove[id] = struct{}{}
+	}
+
+	return s.Purge(func(e Entry) bool {
+		_, ok := remove[e.ID]
+		return ok
+	})
+}
+
+// Purge deletes the entries the filter matches and returns how many were removed
+func (s *Spool) Purge(filter func(Entry) bool) (int, error) {
+	var removed int
+	err := s.update(func(entries []Entry) []Entry {
+		kept := entries[:0]
+		for _, e := range entries {
+			if filter(e) {
+				removed++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		return kept
+	})
+
+	return removed, err
+}
+
+// Failed records another failed attempt of the entry
+func (s *Spool) Failed(id string, cause error) error {
+	return s.update(func(entries []Entry) []Entry {
+		for i := range entries {
+			if entries[i].ID == id {
+				entries[i].Attempts++
+				entries[i].Error = cause.Error()
+				entries[i].LastAttempt = s.now()
+			}
+		}
+		return entries
+	})
+}
+
+// update rewrites the spool file with the entries returned by fn
+func (s *Spool) update(fn func([]Entry) []Entry) error {
+	return s.locked(func() error {
+		entries, err := s.read()
+		if err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		for _, e := range fn(entries) {
+			line, err := json.Marshal(e)
+			if err != nil {
+				return fmt.Errorf("failed to marshal spool entry: %w", err)
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+
+		// the file is replaced at once, so a crash never leaves half an entry behind
+		tmp := s.path + ".tmp"
+		if err = os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+			return fmt.Errorf("failed to write spool: %w", err)
+		}
+		if err = os.Rename(tmp, s.path); err != nil {
+			return fmt.Errorf("failed to replace spool: %w", err)
+		}
+		return nil
+	})
+}
+
+// read returns the entries of the spool file, lines that cannot be decoded are skipped
+func (s *Spool) read() ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err = json.Unmarshal(scanner.Bytes(), &e); err != nil || e.ID == "" {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spool: %w", err)
+	}
+
+	return entries, nil
+}
+
+// locked runs fn while the spool is locked in this process and on the file
+func (s *Spool) locked(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock spool: %w", err)
+	}
+	defer unlock()
+
+	return fn()
+}
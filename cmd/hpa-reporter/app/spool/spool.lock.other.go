@@ -0,0 +1,8 @@
+//go:build !unix
+
+package spool
+
+// lockFile does not lock across processes on this platform
+func lockFile(_ string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,88 @@
+package spool
+
+import (
+	"errors"
+	"github.com/k8shuginn/hpa_reporter/cmd/hpa-reporter/app/message"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpool(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "spool", "dead.jsonl"))
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+
+	if entries, err := s.List(); err != nil || len(entries) != 0 {
+		t.Fatalf("expected an empty spool, got %v %v", entries, err)
+	}
+
+	a, err := s.Add("slack", &message.Data{Name: "web", Namespace: "shop", Level: message.LevelCritical}, errors.New("status 503"))
+	if err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+	b, err := s.Add("webhook", &message.Data{Name: "api", Namespace: "shop"}, errors.New("timeout"))
+	if err != nil {
+		t.Fatalf("failed to add: %v", err)
+	}
+
+	if err = s.Failed(a.ID, errors.New("status 502")); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	entries, err := s.List()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %v %v", entries, err)
+	}
+	if e := entries[0]; e.ID != a.ID || e.Reporter != "slack" || e.Attempts != 2 || e.Error != "status 502" || e.Message.Name != "web" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	if n, err := s.Remove(b.ID, "unknown"); err != nil || n != 1 {
+		t.Errorf("Remove = %d %v, want 1", n, err)
+	}
+	if n, err := s.Purge(func(e Entry) bool { return e.Reporter == "slack" }); err != nil || n != 1 {
+		t.Errorf("Purge = %d %v, want 1", n, err)
+	}
+	if entries, _ = s.List(); len(entries) != 0 {
+		t.Errorf("expected an empty spool, got %v", entries)
+	}
+}
+
+func TestSpoolSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	data := `{"id":"1","reporter":"a","message":{"Name":"web"}}
+{"id":"2","reporter":
+{"id":"3","reporter":"b","message":{"Name":"api"}}
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := Open(path)
+	entries, err := s.List()
+	if err != nil || len(entries) != 2 || entries[1].ID != "3" {
+		t.Errorf("unexpected entries %v %v", entries, err)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	firing := &message.Data{Level: message.LevelCritical, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+	resolved := &message.Data{Level: message.LevelResolved, Alert: message.AlertReplicas, Name: "my-hpa", Namespace: "test"}
+
+	latest := Latest([]Entry{
+		{Reporter: "a", Message: resolved, FailedAt: now.Add(time.Minute)},
+		{Reporter: "a", Message: firing, FailedAt: now},
+		{Reporter: "b", Message: firing, FailedAt: now},
+	})
+
+	if got := latest[Key("a", firing)]; !got.Equal(now.Add(time.Minute)) {
+		t.Errorf("expected the resolved entry to be the latest of a, got %s", got)
+	}
+	if got := latest[Key("b", firing)]; !got.Equal(now) {
+		t.Errorf("expected the entries of b to be kept apart, got %s", got)
+	}
+}
@@ -36,6 +36,13 @@ func init() {
 }
 
 func main() {
+	switch app.Command {
+	case app.ValidateCmd.FullCommand():
+		os.Exit(app.Validate(os.Stdout))
+	case app.SpoolListCmd.FullCommand(), app.SpoolReplayCmd.FullCommand(), app.SpoolPurgeCmd.FullCommand():
+		os.Exit(app.Spool(os.Stdout))
+	}
+
 	reporter := app.NewApp()
 	if err := reporter.Init(); err != nil {
 		logger.Fatal("failed to initialize app", zap.Error(err))
@@ -2,6 +2,8 @@ package k8s
 
 import (
 	"fmt"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -15,6 +17,11 @@ type Client struct {
 	hpaVersion string
 	hpaSii     cache.SharedIndexInformer
 	hpaReg     cache.ResourceEventHandlerRegistration
+
+	dyn       dynamic.Interface
+	dFactory  dynamicinformer.DynamicSharedInformerFactory
+	policySii cache.SharedIndexInformer
+	policyReg cache.ResourceEventHandlerRegistration
 }
 
 func NewClient(hpaEventHandler cache.ResourceEventHandler, kubeConfig string) (*Client, error) {
@@ -36,12 +43,23 @@ func (c *Client) GetHPAVersion() string {
 	return c.hpaVersion
 }
 
+// ListHPAs returns the hpas of the informer cache
+func (c *Client) ListHPAs() []interface{} {
+	return c.hpaSii.GetStore().List()
+}
+
 func (c *Client) Start() {
 	c.shutdown = make(chan struct{})
 	c.iFactory.Start(c.shutdown)
+	if c.dFactory != nil {
+		c.dFactory.Start(c.shutdown)
+	}
 }
 
 func (c *Client) Stop() {
 	_ = c.hpaSii.RemoveEventHandler(c.hpaReg)
+	if c.policySii != nil {
+		_ = c.policySii.RemoveEventHandler(c.policyReg)
+	}
 	close(c.shutdown)
 }
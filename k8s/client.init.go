@@ -2,6 +2,7 @@ package k8s
 
 import (
 	"fmt"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -37,6 +38,11 @@ func (c *Client) initClientSet(kubeConfig string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
+	c.dyn, err = dynamic.NewForConfig(clientCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,121 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"time"
+)
+
+const (
+	PolicyGroup    = "hpa-reporter.k8shuginn.io"
+	PolicyVersion  = "v1alpha1"
+	PolicyResource = "hpareportpolicies"
+	PolicyKind     = "HPAReportPolicy"
+
+	policyStatusTimeout = 10 * time.Second
+)
+
+var (
+	PolicyGVR = schema.GroupVersionResource{Group: PolicyGroup, Version: PolicyVersion, Resource: PolicyResource}
+
+	// ErrPolicyNotInstalled is returned when the HPAReportPolicy CRD is not installed in the cluster
+	ErrPolicyNotInstalled = errors.New("HPAReportPolicy CRD is not installed")
+)
+
+type (
+	// HPAReportPolicy is the alert policy of the hpa in its namespace
+	HPAReportPolicy struct {
+		metav1.TypeMeta   `json:",inline"`
+		metav1.ObjectMeta `json:"metadata,omitempty"`
+
+		Spec   HPAReportPolicySpec   `json:"spec"`
+		Status HPAReportPolicyStatus `json:"status,omitempty"`
+	}
+
+	// HPAReportPolicySpec selects the hpa of the policy namespace and sets their threshold and receivers
+	HPAReportPolicySpec struct {
+		// Selector selects hpa by label, an empty selector selects every hpa of the namespace
+		Selector *metav1.LabelSelector `json:"selector,omitempty"`
+		// Names selects hpa by exact name
+		Names     []string `json:"names,omitempty"`
+		Threshold string   `json:"threshold"`
+		// Receivers are the reporter names that get the alerts, the route of the config still applies
+		Receivers []string `json:"receivers,omitempty"`
+	}
+
+	// HPAReportPolicyStatus is the reconcile result of the policy
+	HPAReportPolicyStatus struct {
+		ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+		Conditions         []metav1.Condition `json:"conditions,omitempty"`
+	}
+)
+
+// ToPolicy converts an informer object into HPAReportPolicy
+func ToPolicy(obj interface{}) (*HPAReportPolicy, error) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object type %T", obj)
+	}
+
+	var policy HPAReportPolicy
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, &policy); err != nil {
+		return nil, fmt.Errorf("failed to convert %s/%s: %w", u.GetNamespace(), u.GetName(), err)
+	}
+
+	return &policy, nil
+}
+
+// WatchPolicies registers the handler to the HPAReportPolicy informer.
+// It returns ErrPolicyNotInstalled if the CRD does not exist, it must be called before Start.
+func (c *Client) WatchPolicies(handler cache.ResourceEventHandler) error {
+	if _, err := c.cs.Discovery().ServerResourcesForGroupVersion(PolicyGVR.GroupVersion().String()); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ErrPolicyNotInstalled
+		}
+		return fmt.Errorf("failed to discover %s: %w", PolicyGVR.GroupVersion(), err)
+	}
+
+	c.dFactory = dynamicinformer.NewDynamicSharedInformerFactory(c.dyn, 0)
+	c.policySii = c.dFactory.ForResource(PolicyGVR).Informer()
+
+	var err error
+	c.policyReg, err = c.policySii.AddEventHandler(handler)
+	if err != nil {
+		return fmt.Errorf("failed to add policy event handler: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePolicyStatus writes the status of the policy back to the cluster
+func (c *Client) UpdatePolicyStatus(policy *HPAReportPolicy) error {
+	policy.APIVersion = PolicyGVR.GroupVersion().String()
+	policy.Kind = PolicyKind
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(policy)
+	if err != nil {
+		return fmt.Errorf("failed to convert policy: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), policyStatusTimeout)
+	defer cancel()
+
+	_, err = c.dyn.Resource(PolicyGVR).Namespace(policy.Namespace).UpdateStatus(ctx, &unstructured.Unstructured{Object: obj}, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update status of %s/%s: %w", policy.Namespace, policy.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	EnvPodNamespace         = "POD_NAMESPACE"
+	ServiceAccountNamespace = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+	DefaultNamespace        = "default"
+
+	secretTimeout = 10 * time.Second
+)
+
+// SecretReader reads reporter credentials from kubernetes secrets
+type SecretReader struct {
+	cs        kubernetes.Interface
+	namespace string
+}
+
+// NewSecretReader creates a secret reader. Secrets without a namespace are read from the namespace of the pod.
+func NewSecretReader(kubeConfig string) (*SecretReader, error) {
+	c := &Client{}
+	if err := c.initClientSet(kubeConfig); err != nil {
+		return nil, fmt.Errorf("[kubernetes] initClientSet error : %w", err)
+	}
+
+	return &SecretReader{cs: c.cs, namespace: podNamespace()}, nil
+}
+
+// ReadSecret returns the value of the key of the secret
+func (r *SecretReader) ReadSecret(namespace, name, key string) (string, error) {
+	if namespace == "" {
+		namespace = r.namespace
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), secretTimeout)
+	defer cancel()
+
+	secret, err := r.cs.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+
+	value, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s has no key %q", namespace, name, key)
+	}
+
+	return string(value), nil
+}
+
+// podNamespace returns the namespace of the running pod
+func podNamespace() string {
+	if ns := os.Getenv(EnvPodNamespace); ns != "" {
+		return ns
+	}
+	if data, err := os.ReadFile(ServiceAccountNamespace); err == nil {
+		if ns := strings.TrimSpace(string(data)); ns != "" {
+			return ns
+		}
+	}
+
+	return DefaultNamespace
+}
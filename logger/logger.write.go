@@ -5,8 +5,8 @@ import (
 )
 
 var (
-	// writer is the global logger
-	writer *zap.Logger
+	// writer is the global logger, it discards everything until SetLogger is called
+	writer = zap.NewNop()
 )
 
 // Debug logs a message at DebugLevel. The message includes any fields passed